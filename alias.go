@@ -0,0 +1,104 @@
+package keystore
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	capitalIWithDotAbove = '\u0130'
+	combiningDotAbove    = '\u0307'
+	capitalSigma         = '\u03a3'
+	smallSigma           = '\u03c3'
+	smallFinalSigma      = '\u03c2'
+)
+
+// toLowerEnglish lower-cases s the same way as Java's String.toLowerCase(Locale.ENGLISH) used by
+// JKS, JCEKS and PKCS12 keystores to normalize aliases. It differs from strings.ToLower
+// in language-insensitive special casing: capital I with dot above becomes i followed by combining dot above
+// and capital sigma at the end of a word becomes final sigma.
+func toLowerEnglish(s string) string {
+	ascii := true
+
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+
+			break
+		}
+	}
+
+	if ascii {
+		return strings.ToLower(s)
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(s))
+
+	for i, r := range s {
+		switch r {
+		case capitalIWithDotAbove:
+			b.WriteRune('i')
+			b.WriteRune(combiningDotAbove)
+		case capitalSigma:
+			if isFinalCased(s, i) {
+				b.WriteRune(smallFinalSigma)
+			} else {
+				b.WriteRune(smallSigma)
+			}
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
+
+// isFinalCased reports whether the character at index i is preceded by a cased letter
+// and is not followed by a cased letter within the same word, see Unicode Final_Sigma condition.
+func isFinalCased(s string, i int) bool {
+	precededByCased := false
+
+	for j := i; j > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:j])
+		if !isWordRune(r) {
+			break
+		}
+
+		if isCased(r) {
+			precededByCased = true
+
+			break
+		}
+
+		j -= size
+	}
+
+	if !precededByCased {
+		return false
+	}
+
+	_, size := utf8.DecodeRuneInString(s[i:])
+
+	for _, r := range s[i+size:] {
+		if !isWordRune(r) {
+			break
+		}
+
+		if isCased(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isCased(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsLower(r) || unicode.IsTitle(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
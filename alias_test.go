@@ -0,0 +1,129 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToLowerEnglish(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		alias string
+		want  string
+	}{
+		{"ascii", "MyAlias-1", "myalias-1"},
+		{"latin", "ÀÉÎÕÜ", "àéîõü"},
+		{"capital i with dot above", "İSTANBUL", "i̇stanbul"},
+		{"dotless i", "ı", "ı"},
+		{"final sigma", "ΟΔΟΣ", "οδος"},
+		{"final sigma before space", "ΟΔΟΣ ΟΔΟΣ", "οδος οδος"},
+		{"sigma inside word", "ΣΟΣΟ", "σοσο"},
+		{"single sigma", "Σ", "σ"},
+		{"cyrillic", "КЛЮЧ", "ключ"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := toLowerEnglish(tt.alias); got != tt.want {
+				t.Errorf("toLowerEnglish(%q) = %q, want %q", tt.alias, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAliasNormalization(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	tce := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	for _, storeType := range []int{JDKStoreType, JCEKSStoreType, PKCS12StoreType} {
+		caseExact := New(WithStoreType(storeType), WithCaseExactAliases())
+		if err := caseExact.SetTrustedCertificateEntry("MyAlias", tce); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := caseExact.Store(&buf, password); err != nil {
+			t.Fatal(err)
+		}
+
+		ks := New()
+		if err := ks.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(ks.Aliases(), []string{"myalias"}) || !ks.IsTrustedCertificateEntry("MYALIAS") {
+			t.Errorf("%d: unexpected aliases %v", storeType, ks.Aliases())
+		}
+
+		later := tce
+		later.CreationTime = tce.CreationTime.Add(time.Hour)
+
+		if err := caseExact.SetTrustedCertificateEntry("myalias", later); err != nil {
+			t.Fatal(err)
+		}
+
+		buf.Reset()
+
+		if err := caseExact.Store(&buf, password); err != nil {
+			t.Fatal(err)
+		}
+
+		failed := New()
+		if err := failed.Load(bytes.NewReader(buf.Bytes()), password); !errors.Is(err, ErrAliasCollision) {
+			t.Errorf("%d: unexpected error for colliding aliases: %v", storeType, err)
+		}
+
+		if len(failed.Aliases()) != 0 {
+			t.Errorf("%d: keystore is changed by failed load %v", storeType, failed.Aliases())
+		}
+
+		var collisions [][2]string
+
+		lastWins := New()
+		if err := lastWins.Load(bytes.NewReader(buf.Bytes()), password, WithOnAliasCollision(
+			func(alias, previous string) error {
+				collisions = append(collisions, [2]string{alias, previous})
+
+				return nil
+			})); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(collisions, [][2]string{{"myalias", "MyAlias"}}) {
+			t.Errorf("%d: unexpected collisions %v", storeType, collisions)
+		}
+
+		creationTime, err := lastWins.CreationTime("myalias")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// PKCS12 keeps no creation time of trusted certificates
+		if !reflect.DeepEqual(lastWins.Aliases(), []string{"myalias"}) ||
+			storeType != PKCS12StoreType && creationTime.Unix() != later.CreationTime.Unix() {
+			t.Errorf("%d: the last entry doesn't win %v %s", storeType, lastWins.Aliases(), creationTime)
+		}
+
+		loaded := New(WithCaseExactAliases(), WithOrderedAliases())
+		if err := loaded.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(loaded.Aliases(), []string{"MyAlias", "myalias"}) {
+			t.Errorf("%d: unexpected case exact aliases %v", storeType, loaded.Aliases())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidCertificates maps aliases to errors of parsing their certificates.
+type ErrInvalidCertificates map[string]error
+
+func (e ErrInvalidCertificates) Error() string {
+	aliases := make([]string, 0, len(e))
+	for alias := range e {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	msgs := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", alias, e[alias]))
+	}
+
+	return "invalid certificates: " + strings.Join(msgs, "; ")
+}
+
+// CertPool returns pool of all TrustedCertificateEntry certificates.
+// If some certificates can't be parsed ErrInvalidCertificates is returned along with the pool of the rest ones.
+func (ks KeyStore) CertPool() (*x509.CertPool, error) {
+	return ks.certPool(false)
+}
+
+// CertPoolWithChains returns pool of all TrustedCertificateEntry certificates
+// and CA certificates from PrivateKeyEntry chains, leaf certificates and other certificates of the chains
+// which are not CAs by basic constraints are skipped.
+// If some certificates can't be parsed ErrInvalidCertificates is returned along with the pool of the rest ones.
+func (ks KeyStore) CertPoolWithChains() (*x509.CertPool, error) {
+	return ks.certPool(true)
+}
+
+func (ks KeyStore) certPool(withChains bool) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	invalid := make(ErrInvalidCertificates)
+
+	for alias, entry := range ks.m {
+		switch typedEntry := entry.(type) {
+		case TrustedCertificateEntry:
+			cert, err := typedEntry.Certificate.X509()
+			if err != nil {
+				invalid[alias] = err
+
+				continue
+			}
+
+			pool.AddCert(cert)
+		case PrivateKeyEntry:
+			if !withChains || len(typedEntry.CertificateChain) < 2 {
+				continue
+			}
+
+			// the leaf certificate and certificates which are not CAs are skipped,
+			// the first invalid certificate of the chain is reported
+			for i := 1; i < len(typedEntry.CertificateChain); i++ {
+				cert, err := typedEntry.CertificateChain[i].X509()
+				if err != nil {
+					if _, ok := invalid[alias]; !ok {
+						invalid[alias] = fmt.Errorf("parse %d certificate in chain: %w", i, err)
+					}
+
+					continue
+				}
+
+				if !cert.BasicConstraintsValid || !cert.IsCA {
+					continue
+				}
+
+				pool.AddCert(cert)
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return pool, invalid
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,96 @@
+package keystore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertPool(t *testing.T) {
+	t.Parallel()
+
+	ks := New()
+
+	tce := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	if err := ks.SetTrustedCertificateEntry("tce-alias", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	// the certificate without basic constraints is not a CA, so it is not trusted
+	pke := PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+			{Type: "X509", Content: readCertificate(t)},
+			{Type: "X509", Content: readPEMBlock(t, "./testdata/ca_cert.pem", "CERTIFICATE")},
+		},
+	}
+
+	if err := ks.SetPrivateKeyEntry("pke-alias", pke, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := ks.CertPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pool.Subjects()) != 1 { // nolint: staticcheck
+		t.Errorf("unexpected number of certificates: %d", len(pool.Subjects())) // nolint: staticcheck
+	}
+
+	ks.DeleteEntry("tce-alias")
+
+	pool, err = ks.CertPoolWithChains()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subjects := pool.Subjects() // nolint: staticcheck
+	if len(subjects) != 1 || !strings.Contains(string(subjects[0]), "Test CA") {
+		t.Errorf("unexpected certificates with chains: %q", subjects)
+	}
+
+	invalidTCE := TrustedCertificateEntry{Certificate: Certificate{Type: "X509", Content: []byte{1, 2, 3}}}
+	if err := ks.SetTrustedCertificateEntry("invalid", invalidTCE); err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid ErrInvalidCertificates
+
+	if _, err := ks.CertPool(); !errors.As(err, &invalid) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := invalid["invalid"]; !ok || len(invalid) != 1 {
+		t.Errorf("unexpected invalid certificates: %v", invalid)
+	}
+
+	ks.DeleteEntry("invalid")
+
+	// the invalid intermediate doesn't drop the root certificate of the chain
+	pke.CertificateChain = []Certificate{
+		pke.CertificateChain[0],
+		{Type: "X509", Content: []byte{1, 2, 3}},
+		pke.CertificateChain[2],
+	}
+
+	if err := ks.SetPrivateKeyEntry("pke-alias", pke, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err = ks.CertPoolWithChains()
+	if !errors.As(err, &invalid) || len(invalid) != 1 || !strings.HasPrefix(invalid["pke-alias"].Error(), "parse 1 ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pool.Subjects()) != 1 { // nolint: staticcheck
+		t.Errorf("valid chain certificates are not added: %d", len(pool.Subjects())) // nolint: staticcheck
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+)
+
+const (
+	dateLayout     = "Jan 2, 2006"
+	validityLayout = "Mon Jan 02 15:04:05 MST 2006"
+	separator      = "\n\n*******************************************\n*******************************************\n\n\n"
+)
+
+// javaSignatureAlgorithms maps signature algorithms to the names used by java.
+var javaSignatureAlgorithms = map[x509.SignatureAlgorithm]string{
+	x509.MD5WithRSA:       "MD5withRSA",
+	x509.SHA1WithRSA:      "SHA1withRSA",
+	x509.SHA256WithRSA:    "SHA256withRSA",
+	x509.SHA384WithRSA:    "SHA384withRSA",
+	x509.SHA512WithRSA:    "SHA512withRSA",
+	x509.SHA256WithRSAPSS: "RSASSA-PSS",
+	x509.SHA384WithRSAPSS: "RSASSA-PSS",
+	x509.SHA512WithRSAPSS: "RSASSA-PSS",
+	x509.ECDSAWithSHA1:    "SHA1withECDSA",
+	x509.ECDSAWithSHA256:  "SHA256withECDSA",
+	x509.ECDSAWithSHA384:  "SHA384withECDSA",
+	x509.ECDSAWithSHA512:  "SHA512withECDSA",
+	x509.PureEd25519:      "Ed25519",
+}
+
+// javaCurveNames maps elliptic curves to the names used by java.
+var javaCurveNames = map[string]string{
+	"P-224": "secp224r1",
+	"P-256": "secp256r1",
+	"P-384": "secp384r1",
+	"P-521": "secp521r1",
+}
+
+func list(opts options) error {
+	ks, err := loadKeyStore(opts, false)
+	if err != nil {
+		return err
+	}
+
+	w := opts.stdout
+
+	if opts.aliasIsSet {
+		if !entryExists(ks, opts.alias) {
+			return fmt.Errorf("alias <%s>: %w", opts.alias, errAliasNotExist)
+		}
+
+		return printEntry(w, ks, opts.alias, opts)
+	}
+
+	aliases := ks.Aliases()
+	sort.Strings(aliases)
+
+	entryNum := len(aliases)
+
+	trailer, hasTrailer := ks.RawTrailer()
+	if hasTrailer {
+		entryNum += int(trailer.EntryNum)
+	}
+
+	fmt.Fprintf(w, "Keystore type: %s\n", storeTypeName(ks.StoreType()))
+	fmt.Fprintf(w, "Keystore provider: %s\n\n", storeProvider(ks.StoreType()))
+
+	if entryNum == 1 {
+		fmt.Fprintf(w, "Your keystore contains 1 entry\n\n")
+	} else {
+		fmt.Fprintf(w, "Your keystore contains %d entries\n\n", entryNum)
+	}
+
+	for _, alias := range aliases {
+		if err := printEntry(w, ks, alias, opts); err != nil {
+			return err
+		}
+
+		if opts.verbose || opts.rfc {
+			fmt.Fprint(w, separator)
+		}
+	}
+
+	if hasTrailer {
+		fmt.Fprintf(w, "%s, %s, UnknownEntry, \n", trailer.Alias, trailer.CreationTime.Format(dateLayout))
+		fmt.Fprintf(w, "Entry with tag %d and %d following entries can't be read\n", trailer.Tag, trailer.EntryNum-1)
+	}
+
+	return nil
+}
+
+func storeProvider(storeType int) string {
+	if storeType == keystore.JCEKSStoreType {
+		return "SunJCE"
+	}
+
+	return "SUN"
+}
+
+func printEntry(w io.Writer, ks keystore.KeyStore, alias string, opts options) error {
+	creationTime, err := ks.CreationTime(alias)
+	if err != nil {
+		return err
+	}
+
+	var (
+		entryType string
+		chain     []keystore.Certificate
+	)
+
+	switch {
+	case ks.IsPrivateKeyEntry(alias):
+		entryType = "PrivateKeyEntry"
+
+		if chain, err = ks.GetPrivateKeyEntryCertificateChain(alias); err != nil {
+			return err
+		}
+	case ks.IsTrustedCertificateEntry(alias):
+		entryType = "trustedCertEntry"
+
+		tce, err := ks.GetTrustedCertificateEntry(alias)
+		if err != nil {
+			return err
+		}
+
+		chain = []keystore.Certificate{tce.Certificate}
+	case ks.IsSecurityKeyEntry(alias):
+		entryType = "SecretKeyEntry"
+	}
+
+	if !opts.verbose && !opts.rfc {
+		fmt.Fprintf(w, "%s, %s, %s, \n", alias, creationTime.Format(dateLayout), entryType)
+
+		if len(chain) > 0 {
+			fingerprint := sha256.Sum256(chain[0].Content)
+			fmt.Fprintf(w, "Certificate fingerprint (SHA-256): %s\n", formatFingerprint(fingerprint[:]))
+		}
+
+		return nil
+	}
+
+	fmt.Fprintf(w, "Alias name: %s\n", alias)
+	fmt.Fprintf(w, "Creation date: %s\n", creationTime.Format(dateLayout))
+	fmt.Fprintf(w, "Entry type: %s\n", entryType)
+
+	switch entryType {
+	case "PrivateKeyEntry":
+		fmt.Fprintf(w, "Certificate chain length: %d\n", len(chain))
+
+		for i, cert := range chain {
+			fmt.Fprintf(w, "Certificate[%d]:\n", i+1)
+
+			if err := printCertificate(w, cert, opts.rfc); err != nil {
+				return fmt.Errorf("print certificate <%s>: %w", alias, err)
+			}
+		}
+	case "trustedCertEntry":
+		fmt.Fprintln(w)
+
+		if err := printCertificate(w, chain[0], opts.rfc); err != nil {
+			return fmt.Errorf("print certificate <%s>: %w", alias, err)
+		}
+	}
+
+	return nil
+}
+
+func printCertificate(w io.Writer, cert keystore.Certificate, rfc bool) error {
+	if rfc {
+		return pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Content})
+	}
+
+	c, err := cert.X509()
+	if err != nil {
+		return err
+	}
+
+	sha1Fingerprint := sha1.Sum(cert.Content) //nolint:gosec
+	sha256Fingerprint := sha256.Sum256(cert.Content)
+
+	fmt.Fprintf(w, "Owner: %s\n", c.Subject)
+	fmt.Fprintf(w, "Issuer: %s\n", c.Issuer)
+	fmt.Fprintf(w, "Serial number: %s\n", c.SerialNumber.Text(16))
+	fmt.Fprintf(w, "Valid from: %s until: %s\n", c.NotBefore.Format(validityLayout), c.NotAfter.Format(validityLayout))
+	fmt.Fprintf(w, "Certificate fingerprints:\n")
+	fmt.Fprintf(w, "\t SHA1: %s\n", formatFingerprint(sha1Fingerprint[:]))
+	fmt.Fprintf(w, "\t SHA256: %s\n", formatFingerprint(sha256Fingerprint[:]))
+	fmt.Fprintf(w, "Signature algorithm name: %s\n", signatureAlgorithmName(c.SignatureAlgorithm))
+	fmt.Fprintf(w, "Subject Public Key Algorithm: %s\n", publicKeyDescription(c.PublicKey))
+	fmt.Fprintf(w, "Version: %d\n", c.Version)
+
+	return nil
+}
+
+func formatFingerprint(fingerprint []byte) string {
+	parts := make([]string, 0, len(fingerprint))
+	for _, b := range fingerprint {
+		parts = append(parts, fmt.Sprintf("%02X", b))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func signatureAlgorithmName(algorithm x509.SignatureAlgorithm) string {
+	if name, ok := javaSignatureAlgorithms[algorithm]; ok {
+		return name
+	}
+
+	return algorithm.String()
+}
+
+func publicKeyDescription(key interface{}) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("%d-bit RSA key", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		params := k.Curve.Params()
+
+		name, ok := javaCurveNames[params.Name]
+		if !ok {
+			name = params.Name
+		}
+
+		return fmt.Sprintf("%d-bit EC (%s) key", params.BitSize, name)
+	case ed25519.PublicKey:
+		return "Ed25519 key"
+	default:
+		return fmt.Sprintf("%T key", key)
+	}
+}
@@ -0,0 +1,492 @@
+// Command gokeytool manages JKS and JCEKS keystores the way java keytool does, without a JRE.
+//
+// Supported commands and their options:
+//
+//	-list        [-v | -rfc] [-alias alias] -keystore keystore [-storepass password]
+//	-importcert  [-alias alias] -file file [-storetype type] -keystore keystore -storepass password [-noprompt]
+//	-exportcert  [-alias alias] [-file file] [-rfc] -keystore keystore [-storepass password]
+//	-delete      -alias alias -keystore keystore -storepass password
+//	-changealias [-alias alias] -destalias alias [-keypass password] -keystore keystore -storepass password
+//	-storepasswd -new password -keystore keystore -storepass password
+//	-keypasswd   [-alias alias] [-keypass password] -new password -keystore keystore -storepass password
+//
+// Passwords are never prompted, the key password defaults to the store password as keytool does.
+// -list and -exportcert can be used without -storepass, the keystore integrity is not checked then.
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+)
+
+const (
+	defaultAlias    = "mykey"
+	defaultKeystore = ".keystore"
+)
+
+var (
+	errUnknownCommand   = errors.New("unknown command")
+	errMissingOption    = errors.New("missing option")
+	errAliasNotExist    = errors.New("alias does not exist")
+	errAliasExists      = errors.New("alias already exists")
+	errUnknownStoreType = errors.New("unknown keystore type")
+)
+
+// options are the keytool options shared by all commands.
+type options struct {
+	keystore     string
+	storeType    string
+	storePass    string
+	keyPass      string
+	newPass      string
+	alias        string
+	destAlias    string
+	file         string
+	verbose      bool
+	rfc          bool
+	noPrompt     bool
+	stdout       io.Writer
+	stderr       io.Writer
+	aliasIsSet   bool
+	keyPassIsSet bool
+}
+
+type command func(opts options) error
+
+// readOnlyCommands can read the keystore without -storepass, its integrity is not checked then.
+var readOnlyCommands = map[string]bool{
+	"-list":       true,
+	"-exportcert": true,
+}
+
+const integrityWarning = `
+*****************  WARNING WARNING WARNING  *****************
+* The integrity of the information stored in your keystore  *
+* has NOT been verified!  In order to verify its integrity, *
+* you must provide your keystore password.                  *
+*****************  WARNING WARNING WARNING  *****************
+
+`
+
+var commands = map[string]command{
+	"-list":        list,
+	"-importcert":  importCert,
+	"-exportcert":  exportCert,
+	"-delete":      deleteEntry,
+	"-changealias": changeAlias,
+	"-storepasswd": storePasswd,
+	"-keypasswd":   keyPasswd,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "gokeytool error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected one of %s: %w", strings.Join(commandNames(), ", "), errUnknownCommand)
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("got %s: %w", args[0], errUnknownCommand)
+	}
+
+	opts := options{stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("gokeytool "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.keystore, "keystore", defaultKeystore, "keystore `file`")
+	fs.StringVar(&opts.storeType, "storetype", "", "keystore type of a new keystore: JKS or JCEKS")
+	fs.StringVar(&opts.storePass, "storepass", "", "keystore `password`")
+	fs.StringVar(&opts.keyPass, "keypass", "", "key `password`, the store password is used if empty")
+	fs.StringVar(&opts.newPass, "new", "", "new `password`")
+	fs.StringVar(&opts.alias, "alias", defaultAlias, "`alias` of the entry")
+	fs.StringVar(&opts.destAlias, "destalias", "", "destination `alias`")
+	fs.StringVar(&opts.file, "file", "", "input or output `file`")
+	fs.BoolVar(&opts.verbose, "v", false, "verbose output")
+	fs.BoolVar(&opts.rfc, "rfc", false, "output in RFC style")
+	fs.BoolVar(&opts.noPrompt, "noprompt", false, "do not prompt, kept for keytool compatibility")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("got unexpected arguments %v", fs.Args())
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "alias":
+			opts.aliasIsSet = true
+		case "keypass":
+			opts.keyPassIsSet = true
+		}
+	})
+
+	if opts.storePass == "" && !readOnlyCommands[args[0]] {
+		return fmt.Errorf("-storepass: %w", errMissingOption)
+	}
+
+	if !opts.keyPassIsSet {
+		opts.keyPass = opts.storePass
+	}
+
+	return cmd(opts)
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func parseStoreType(storeType string) (int, error) {
+	switch strings.ToUpper(storeType) {
+	case "", "JKS":
+		return keystore.JDKStoreType, nil
+	case "JCEKS":
+		return keystore.JCEKSStoreType, nil
+	case "PKCS12":
+		return keystore.PKCS12StoreType, nil
+	default:
+		return 0, fmt.Errorf("got %s: %w", storeType, errUnknownStoreType)
+	}
+}
+
+func storeTypeName(storeType int) string {
+	switch storeType {
+	case keystore.JCEKSStoreType:
+		return "JCEKS"
+	case keystore.PKCS12StoreType:
+		return "PKCS12"
+	default:
+		return "JKS"
+	}
+}
+
+// loadKeyStore reads the keystore file. A new keystore of -storetype is returned
+// if the file does not exist and create is true.
+func loadKeyStore(opts options, create bool) (keystore.KeyStore, error) {
+	storeType, err := parseStoreType(opts.storeType)
+	if err != nil {
+		return keystore.KeyStore{}, err
+	}
+
+	f, err := os.Open(opts.keystore)
+	if os.IsNotExist(err) && create {
+		return keystore.New(keystore.WithStoreType(storeType)), nil
+	}
+
+	if err != nil {
+		return keystore.KeyStore{}, fmt.Errorf("open keystore: %w", err)
+	}
+
+	defer f.Close()
+
+	var loadOptions []keystore.LoadOption
+
+	if opts.storePass == "" {
+		loadOptions = append(loadOptions, keystore.WithoutIntegrityCheck())
+
+		fmt.Fprint(opts.stderr, integrityWarning)
+	}
+
+	ks := keystore.New()
+	if err := ks.Load(f, []byte(opts.storePass), loadOptions...); err != nil {
+		return keystore.KeyStore{}, fmt.Errorf("load keystore: %w", err)
+	}
+
+	if err := checkStoreType(ks, opts.storeType, storeType); err != nil {
+		return keystore.KeyStore{}, err
+	}
+
+	return ks, nil
+}
+
+// checkStoreType returns errUnknownStoreType if the keystore is not of the type set by -storetype.
+func checkStoreType(ks keystore.KeyStore, name string, storeType int) error {
+	if name != "" && ks.StoreType() != storeType {
+		return fmt.Errorf("got %s keystore, expected %s: %w", storeTypeName(ks.StoreType()), name, errUnknownStoreType)
+	}
+
+	return nil
+}
+
+// storeKeyStore replaces the keystore file atomically, so the file is not truncated on errors.
+func storeKeyStore(ks keystore.KeyStore, path string, password []byte) error {
+	if err := ks.StoreFile(path, password); err != nil {
+		return fmt.Errorf("store keystore: %w", err)
+	}
+
+	return nil
+}
+
+func entryExists(ks keystore.KeyStore, alias string) bool {
+	return ks.IsPrivateKeyEntry(alias) || ks.IsTrustedCertificateEntry(alias) || ks.IsSecurityKeyEntry(alias)
+}
+
+func importCert(opts options) error {
+	if opts.file == "" {
+		return fmt.Errorf("-file: %w", errMissingOption)
+	}
+
+	ks, err := loadKeyStore(opts, true)
+	if err != nil {
+		return err
+	}
+
+	if entryExists(ks, opts.alias) {
+		return fmt.Errorf("certificate not imported, alias <%s>: %w", opts.alias, errAliasExists)
+	}
+
+	data, err := ioutil.ReadFile(opts.file)
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	if _, err := x509.ParseCertificate(data); err != nil {
+		return fmt.Errorf("parse certificate: %w", err)
+	}
+
+	tce := keystore.TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  keystore.Certificate{Type: "X.509", Content: data},
+	}
+
+	if err := ks.SetTrustedCertificateEntry(opts.alias, tce); err != nil {
+		return err
+	}
+
+	if err := storeKeyStore(ks, opts.keystore, []byte(opts.storePass)); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(opts.stderr, "Certificate was added to keystore")
+
+	return nil
+}
+
+func exportCert(opts options) error {
+	ks, err := loadKeyStore(opts, false)
+	if err != nil {
+		return err
+	}
+
+	var cert keystore.Certificate
+
+	switch {
+	case ks.IsTrustedCertificateEntry(opts.alias):
+		tce, err := ks.GetTrustedCertificateEntry(opts.alias)
+		if err != nil {
+			return err
+		}
+
+		cert = tce.Certificate
+	case ks.IsPrivateKeyEntry(opts.alias):
+		chain, err := ks.GetPrivateKeyEntryCertificateChain(opts.alias)
+		if err != nil {
+			return err
+		}
+
+		if len(chain) == 0 {
+			return fmt.Errorf("alias <%s> has no certificate", opts.alias)
+		}
+
+		cert = chain[0]
+	default:
+		return fmt.Errorf("alias <%s>: %w", opts.alias, errAliasNotExist)
+	}
+
+	data := cert.Content
+	if opts.rfc {
+		data = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Content})
+	}
+
+	if opts.file == "" {
+		_, err := opts.stdout.Write(data)
+
+		return err
+	}
+
+	if err := ioutil.WriteFile(opts.file, data, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("write certificate: %w", err)
+	}
+
+	fmt.Fprintf(opts.stderr, "Certificate stored in file <%s>\n", opts.file)
+
+	return nil
+}
+
+func deleteEntry(opts options) error {
+	if !opts.aliasIsSet {
+		return fmt.Errorf("-alias: %w", errMissingOption)
+	}
+
+	ks, err := loadKeyStore(opts, false)
+	if err != nil {
+		return err
+	}
+
+	if !entryExists(ks, opts.alias) {
+		return fmt.Errorf("alias <%s>: %w", opts.alias, errAliasNotExist)
+	}
+
+	ks.DeleteEntry(opts.alias)
+
+	return storeKeyStore(ks, opts.keystore, []byte(opts.storePass))
+}
+
+func changeAlias(opts options) error {
+	if opts.destAlias == "" {
+		return fmt.Errorf("-destalias: %w", errMissingOption)
+	}
+
+	ks, err := loadKeyStore(opts, false)
+	if err != nil {
+		return err
+	}
+
+	if entryExists(ks, opts.destAlias) {
+		return fmt.Errorf("alias <%s>: %w", opts.destAlias, errAliasExists)
+	}
+
+	keyPass := []byte(opts.keyPass)
+
+	switch {
+	case ks.IsTrustedCertificateEntry(opts.alias):
+		tce, err := ks.GetTrustedCertificateEntry(opts.alias)
+		if err != nil {
+			return err
+		}
+
+		err = ks.SetTrustedCertificateEntry(opts.destAlias, tce)
+		if err != nil {
+			return err
+		}
+	case ks.IsPrivateKeyEntry(opts.alias):
+		if err := setPrivateKeyEntry(ks, opts.alias, opts.destAlias, keyPass, keyPass); err != nil {
+			return err
+		}
+	case ks.IsSecurityKeyEntry(opts.alias):
+		if err := setSecurityKeyEntry(ks, opts.alias, opts.destAlias, keyPass, keyPass); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("alias <%s>: %w", opts.alias, errAliasNotExist)
+	}
+
+	ks.DeleteEntry(opts.alias)
+
+	return storeKeyStore(ks, opts.keystore, []byte(opts.storePass))
+}
+
+func storePasswd(opts options) error {
+	if opts.newPass == "" {
+		return fmt.Errorf("-new: %w", errMissingOption)
+	}
+
+	if opts.storePass == "" {
+		return fmt.Errorf("-storepass: %w", errMissingOption)
+	}
+
+	storeType, err := parseStoreType(opts.storeType)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(opts.keystore)
+	if err != nil {
+		return fmt.Errorf("open keystore: %w", err)
+	}
+
+	defer f.Close()
+
+	// keys of PKCS12 keystores are protected by the store password, so they are re-encrypted too
+	var changed bytes.Buffer
+	if err := keystore.ChangeStorePassword(f, &changed, []byte(opts.storePass), []byte(opts.newPass)); err != nil {
+		return fmt.Errorf("change store password: %w", err)
+	}
+
+	ks := keystore.New()
+	if err := ks.Load(&changed, []byte(opts.newPass)); err != nil {
+		return fmt.Errorf("load keystore: %w", err)
+	}
+
+	if err := checkStoreType(ks, opts.storeType, storeType); err != nil {
+		return err
+	}
+
+	return storeKeyStore(ks, opts.keystore, []byte(opts.newPass))
+}
+
+func keyPasswd(opts options) error {
+	if opts.newPass == "" {
+		return fmt.Errorf("-new: %w", errMissingOption)
+	}
+
+	ks, err := loadKeyStore(opts, false)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case ks.IsPrivateKeyEntry(opts.alias), ks.IsSecurityKeyEntry(opts.alias):
+		err = ks.ChangeEntryPassword(opts.alias, []byte(opts.keyPass), []byte(opts.newPass))
+	case ks.IsTrustedCertificateEntry(opts.alias):
+		err = fmt.Errorf("alias <%s> has no key", opts.alias)
+	default:
+		err = fmt.Errorf("alias <%s>: %w", opts.alias, errAliasNotExist)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return storeKeyStore(ks, opts.keystore, []byte(opts.storePass))
+}
+
+// setPrivateKeyEntry decrypts the private key of the alias and sets it encrypted with newPassword by destAlias.
+func setPrivateKeyEntry(ks keystore.KeyStore, alias, destAlias string, password, newPassword []byte) error {
+	pke, err := ks.GetPrivateKeyEntry(alias, password)
+	if err != nil {
+		return fmt.Errorf("get private key entry <%s>: %w", alias, err)
+	}
+
+	return ks.SetPrivateKeyEntry(destAlias, pke, newPassword)
+}
+
+// setSecurityKeyEntry decrypts the security key of the alias and sets it encrypted with newPassword by destAlias.
+func setSecurityKeyEntry(ks keystore.KeyStore, alias, destAlias string, password, newPassword []byte) error {
+	ske, err := ks.GetSecurityKeyEntry(alias, password)
+	if err != nil {
+		return fmt.Errorf("get secret key entry <%s>: %w", alias, err)
+	}
+
+	return ks.SetSecurityKeyEntry(destAlias, ske, newPassword)
+}
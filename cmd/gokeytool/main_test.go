@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+)
+
+func copyKeyStore(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Join("../../testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func runCommand(t *testing.T, args ...string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("%v: %s", args, err)
+	}
+
+	return stdout.String()
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gokeytool")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ksPath := copyKeyStore(t, dir, "keystore_keypass.jks")
+	certPath := filepath.Join("../../testdata", "ca_cert.pem")
+	exportPath := filepath.Join(dir, "exported.pem")
+
+	out := runCommand(t, "-list", "-keystore", ksPath, "-storepass", "password")
+	if !strings.Contains(out, "Your keystore contains 1 entry") || !strings.Contains(out, "alias, ") {
+		t.Errorf("unexpected list output:\n%s", out)
+	}
+
+	runCommand(t, "-importcert", "-keystore", ksPath, "-storepass", "password",
+		"-alias", "ca", "-file", certPath, "-noprompt")
+
+	var stdout, stderr bytes.Buffer
+
+	err = run([]string{"-importcert", "-keystore", ksPath, "-storepass", "password",
+		"-alias", "ca", "-file", certPath}, &stdout, &stderr)
+	if !errors.Is(err, errAliasExists) {
+		t.Errorf("unexpected error importing existing alias: %v", err)
+	}
+
+	runCommand(t, "-exportcert", "-keystore", ksPath, "-storepass", "password",
+		"-alias", "ca", "-rfc", "-file", exportPath)
+
+	expected, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := ioutil.ReadFile(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(exported)) {
+		t.Errorf("exported certificate differs from the imported one")
+	}
+
+	runCommand(t, "-keypasswd", "-keystore", ksPath, "-storepass", "password",
+		"-alias", "alias", "-keypass", "keypassword", "-new", "newkeypassword")
+	runCommand(t, "-changealias", "-keystore", ksPath, "-storepass", "password",
+		"-alias", "alias", "-destalias", "renamed", "-keypass", "newkeypassword")
+	runCommand(t, "-storepasswd", "-keystore", ksPath, "-storepass", "password", "-new", "newpassword")
+	runCommand(t, "-delete", "-keystore", ksPath, "-storepass", "newpassword", "-alias", "ca")
+
+	out = runCommand(t, "-list", "-v", "-keystore", ksPath, "-storepass", "newpassword")
+	for _, s := range []string{
+		"Your keystore contains 1 entry",
+		"Alias name: renamed",
+		"Entry type: PrivateKeyEntry",
+		"Certificate chain length: 1",
+		"SHA256: F6:C6:73:31",
+		"Signature algorithm name: SHA256withRSA",
+		"Subject Public Key Algorithm: 2048-bit RSA key",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("verbose list output does not contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestCommandsJCEKS(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gokeytool")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ksPath := filepath.Join(dir, "keystore.jceks")
+
+	runCommand(t, "-importcert", "-keystore", ksPath, "-storepass", "password", "-storetype", "JCEKS",
+		"-alias", "ca", "-file", filepath.Join("../../testdata", "ca_cert.pem"))
+
+	out := runCommand(t, "-list", "-rfc", "-keystore", ksPath, "-storepass", "password")
+	for _, s := range []string{"Keystore type: JCEKS", "Entry type: trustedCertEntry", "-----BEGIN CERTIFICATE-----"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("rfc list output does not contain %q:\n%s", s, out)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if err := run([]string{"-list", "-keystore", ksPath}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stderr.String(), "has NOT been verified") {
+		t.Errorf("integrity warning is not printed:\n%s", stderr.String())
+	}
+
+	err = run([]string{"-list", "-keystore", ksPath, "-storepass", "password", "-storetype", "JKS"}, &stdout, &stderr)
+	if !errors.Is(err, errUnknownStoreType) {
+		t.Errorf("unexpected error for wrong store type: %v", err)
+	}
+
+	if err := run([]string{"-unknown"}, &stdout, &stderr); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("unexpected error for unknown command: %v", err)
+	}
+}
+
+func TestStorePasswdPKCS12(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gokeytool")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ksPath := copyKeyStore(t, dir, "keystore.p12")
+
+	var stdout, stderr bytes.Buffer
+
+	err = run([]string{"-storepasswd", "-keystore", ksPath, "-storepass", "password", "-storetype", "JKS",
+		"-new", "newpassword"}, &stdout, &stderr)
+	if !errors.Is(err, errUnknownStoreType) {
+		t.Errorf("unexpected error for wrong store type: %v", err)
+	}
+
+	runCommand(t, "-storepasswd", "-keystore", ksPath, "-storepass", "password", "-storetype", "PKCS12",
+		"-new", "newpassword")
+
+	ks := keystore.New()
+	if err := ks.LoadFile(ksPath, []byte("newpassword")); err != nil {
+		t.Fatal(err)
+	}
+
+	// keys of PKCS12 keystores are protected by the store password
+	if _, err := ks.GetPrivateKeyEntry("alias", []byte("newpassword")); err != nil {
+		t.Errorf("private key is not re-encrypted with the new store password: %v", err)
+	}
+}
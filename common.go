@@ -1,15 +1,24 @@
 package keystore
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 const (
 	jksmagic   uint32 = 0xfeedfeed
 	jceksmagic uint32 = 0xcececece
+	// BouncyCastle keystores start with the version instead of magic.
+	bksVersion1 uint32 = 1
+	bksVersion2 uint32 = 2
 )
 
+const magicLen = 4
+
 const (
 	version01 uint32 = 1
 	version02 uint32 = 2
@@ -25,15 +34,132 @@ var byteOrder = binary.BigEndian
 
 var whitenerMessage = []byte("Mighty Aphrodite")
 
+// passwordBytes encodes UTF-8 password into UTF-16BE as Java does for char[] passwords.
+// Bytes which are not valid UTF-8 are treated as ISO-8859-1 characters for compatibility.
 func passwordBytes(password []byte) []byte {
 	result := make([]byte, 0, len(password)*2)
+
+	for len(password) > 0 {
+		r, size := utf8.DecodeRune(password)
+		if r == utf8.RuneError && size == 1 {
+			r = rune(password[0])
+		}
+
+		password = password[size:]
+
+		if r >= surrogateSelf {
+			r1, r2 := utf16.EncodeRune(r)
+			result = append(result, byte(r1>>8), byte(r1), byte(r2>>8), byte(r2))
+
+			continue
+		}
+
+		result = append(result, byte(r>>8), byte(r))
+	}
+
+	return result
+}
+
+// utf8Password encodes password into UTF-8 as Java does for PBKDF2 passwords, bytes which are not valid UTF-8
+// are treated as ISO-8859-1 characters as passwordBytes does. It returns nil if the password is valid UTF-8.
+func utf8Password(password []byte) []byte {
+	if utf8.Valid(password) {
+		return nil
+	}
+
+	result := make([]byte, 0, len(password)*2)
+
+	var buf [utf8.UTFMax]byte
+
+	for len(password) > 0 {
+		r, size := utf8.DecodeRune(password)
+		if r == utf8.RuneError && size == 1 {
+			r = rune(password[0])
+		}
+
+		password = password[size:]
+		result = append(result, buf[:utf8.EncodeRune(buf[:], r)]...)
+	}
+
+	zeroing(buf[:])
+
+	return result
+}
+
+// legacyPasswordBytes encodes password byte by byte into UTF-16BE as older versions of the library did.
+// It returns nil if the encoding is the same as of passwordBytes, which is true for ASCII passwords.
+func legacyPasswordBytes(password []byte) []byte {
+	result := make([]byte, 0, len(password)*2)
 	for _, b := range password {
 		result = append(result, 0, b)
 	}
 
+	if encoded := passwordBytes(password); bytes.Equal(result, encoded) {
+		zeroing(encoded)
+		zeroing(result)
+
+		return nil
+	}
+
 	return result
 }
 
+// surrogateSelf is the first rune encoded by UTF-16 surrogate pair.
+const surrogateSelf = 0x10000
+
+// encodeModifiedUTF8 encodes s as Java's DataOutput.writeUTF does without the length prefix:
+// the characters are UTF-16 code units, zero is encoded with two bytes,
+// supplementary characters are encoded as surrogate pairs of three bytes each.
+func encodeModifiedUTF8(s string) []byte {
+	result := make([]byte, 0, len(s))
+
+	for _, c := range utf16.Encode([]rune(s)) {
+		switch {
+		case c != 0 && c < 0x80:
+			result = append(result, byte(c))
+		case c < 0x800:
+			result = append(result, 0xc0|byte(c>>6), 0x80|byte(c&0x3f))
+		default:
+			result = append(result, 0xe0|byte(c>>12), 0x80|byte((c>>6)&0x3f), 0x80|byte(c&0x3f))
+		}
+	}
+
+	return result
+}
+
+// decodeModifiedUTF8 decodes data encoded as Java's DataOutput.writeUTF does without the length prefix.
+func decodeModifiedUTF8(data []byte) (string, error) {
+	chars := make([]uint16, 0, len(data))
+
+	for i := 0; i < len(data); {
+		b := data[i]
+
+		switch {
+		case b < 0x80:
+			chars = append(chars, uint16(b))
+			i++
+		case b&0xe0 == 0xc0:
+			if i+1 >= len(data) || data[i+1]&0xc0 != 0x80 {
+				return "", fmt.Errorf("got malformed input around byte %d: %w", i, ErrMalformedString)
+			}
+
+			chars = append(chars, uint16(b&0x1f)<<6|uint16(data[i+1]&0x3f))
+			i += 2
+		case b&0xf0 == 0xe0:
+			if i+2 >= len(data) || data[i+1]&0xc0 != 0x80 || data[i+2]&0xc0 != 0x80 {
+				return "", fmt.Errorf("got malformed input around byte %d: %w", i, ErrMalformedString)
+			}
+
+			chars = append(chars, uint16(b&0x0f)<<12|uint16(data[i+1]&0x3f)<<6|uint16(data[i+2]&0x3f))
+			i += 3
+		default:
+			return "", fmt.Errorf("got malformed input around byte %d: %w", i, ErrMalformedString)
+		}
+	}
+
+	return string(utf16.Decode(chars)), nil
+}
+
 func zeroing(buf []byte) {
 	for i := 0; i < len(buf); i++ {
 		buf[i] = 0
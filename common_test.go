@@ -1,9 +1,12 @@
 package keystore
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestZeroing(t *testing.T) {
@@ -54,6 +57,11 @@ func TestPasswordBytes(t *testing.T) {
 			t.Errorf("read random bytes: %v", err)
 		}
 
+		// ASCII passwords are encoded as zero byte followed by the password byte.
+		for j := range input {
+			input[j] &= 0x7f
+		}
+
 		output := make([]byte, len(input)*2)
 
 		for j, k := 0, 0; j < len(output); j, k = j+2, k+1 {
@@ -71,3 +79,136 @@ func TestPasswordBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestPasswordBytesUnicode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		password []byte
+		want     []byte
+	}{
+		{"latin", PasswordFromString("pä"), []byte{0x00, 0x70, 0x00, 0xe4}},
+		{"euro", PasswordFromRunes([]rune("€")), []byte{0x20, 0xac}},
+		{"cyrillic", PasswordFromString("пароль"), []byte{
+			0x04, 0x3f, 0x04, 0x30, 0x04, 0x40, 0x04, 0x3e, 0x04, 0x3b, 0x04, 0x4c,
+		}},
+		{"supplementary", PasswordFromRunes([]rune{0x1f600}), []byte{0xd8, 0x3d, 0xde, 0x00}},
+		{"not utf-8", []byte{0x70, 0xe4}, []byte{0x00, 0x70, 0x00, 0xe4}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := passwordBytes(tt.password); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("passwordBytes(%v) = %x, want %x", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModifiedUTF8(t *testing.T) {
+	t.Parallel()
+
+	// Expected values are encodings of DataOutputStream.writeUTF without the length prefix.
+	tests := []struct {
+		name    string
+		decoded string
+		encoded []byte
+	}{
+		{"ascii", "alias", []byte("alias")},
+		{"zero", "a\x00b", []byte{0x61, 0xc0, 0x80, 0x62}},
+		{"two bytes", "é", []byte{0xc3, 0xa9}},
+		{"three bytes", "€", []byte{0xe2, 0x82, 0xac}},
+		{"supplementary", "\U0001f600", []byte{0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := encodeModifiedUTF8(tt.decoded); !reflect.DeepEqual(got, tt.encoded) {
+				t.Errorf("encodeModifiedUTF8(%q) = %x, want %x", tt.decoded, got, tt.encoded)
+			}
+
+			got, err := decodeModifiedUTF8(tt.encoded)
+			if err != nil || got != tt.decoded {
+				t.Errorf("decodeModifiedUTF8(%x) = %q, %v, want %q", tt.encoded, got, err, tt.decoded)
+			}
+		})
+	}
+
+	for _, malformed := range [][]byte{{0x80}, {0xc3}, {0xe2, 0x82}, {0xf0, 0x9f, 0x98, 0x80}, {0xc3, 0x41}} {
+		if _, err := decodeModifiedUTF8(malformed); !errors.Is(err, ErrMalformedString) {
+			t.Errorf("unexpected error for malformed string %x: %v", malformed, err)
+		}
+	}
+}
+
+func TestUnicodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	password := PasswordFromString("пароль-ä")
+	alias := "ключ-\U0001f600"
+
+	ks := New()
+	if err := ks.SetPrivateKeyEntry(alias, PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), encodeModifiedUTF8(alias)) {
+		t.Error("alias is not encoded with modified utf-8")
+	}
+
+	loaded := New()
+	if err := loaded.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loaded.GetPrivateKeyEntry(alias, password); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadKeytoolUnicode(t *testing.T) {
+	t.Parallel()
+
+	data := readKeytoolKeyStore(t, "keytool_unicode.jks")
+	password := PasswordFromString("пароль€")
+	alias := "ключ-\U0001f600"
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(data), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ks.Aliases(), []string{alias}) {
+		t.Fatalf("unexpected aliases %q", ks.Aliases())
+	}
+
+	if _, err := ks.GetPrivateKeyEntry(alias, PasswordFromString("ключ-пароль")); err != nil {
+		t.Error(err)
+	}
+
+	// Java encodes the alias and the password digest the same way
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("stored keystore is not equal to the one generated by keytool")
+	}
+}
@@ -0,0 +1,112 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMissingPassword = errors.New("missing password")
+
+// Convert returns a copy of the keystore with the target storeType.
+// Private keys are decrypted with passwords of their aliases and encrypted again for the target type
+// with the same passwords. Aliases of passwords are converted as the keystore converts them,
+// so they may be in the original case. Entries the target type can't hold are reported with ErrUnsupportedEntryType,
+// the raw trailer is kept only if the type is not changed.
+// It is strongly recommended to fill password slices with zero after usage.
+func Convert(ks KeyStore, storeType int, passwords map[string][]byte) (KeyStore, error) {
+	converted := New(WithStoreType(storeType))
+	converted.ordered = ks.ordered
+	converted.caseExact = ks.caseExact
+	converted.insertionOrdered = ks.insertionOrdered
+
+	passwords = ks.convertPasswordAliases(passwords)
+
+	if ks.trailer != nil {
+		if storeType != ks.storeType {
+			return KeyStore{}, fmt.Errorf("convert raw trailer %q: %w", ks.trailer.Alias, ErrUnsupportedEntryType)
+		}
+
+		converted.trailer = ks.trailer
+		converted.version = ks.version
+	}
+
+	for _, alias := range ks.storeAliases() {
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			pke, err := convertPrivateKeyEntry(typedEntry, ks.storeType, storeType, passwords[alias])
+			if err != nil {
+				return KeyStore{}, fmt.Errorf("convert private key entry %q: %w", alias, err)
+			}
+
+			converted.setEntry(alias, pke)
+		case TrustedCertificateEntry:
+			if err := checkCertificateType(storeType, typedEntry.Certificate); err != nil {
+				return KeyStore{}, fmt.Errorf("convert trusted certificate entry %q: %w", alias, err)
+			}
+
+			converted.setEntry(alias, typedEntry)
+		case SecurityKeyEntry:
+			if storeType != JCEKSStoreType {
+				return KeyStore{}, fmt.Errorf("convert security key entry %q: %w", alias, ErrUnsupportedEntryType)
+			}
+
+			converted.setEntry(alias, typedEntry)
+		default:
+			return KeyStore{}, fmt.Errorf("convert entry %q: got invalid entry", alias)
+		}
+	}
+
+	return converted, nil
+}
+
+// convertPasswordAliases returns passwords by aliases converted the same way as in setters.
+func (ks KeyStore) convertPasswordAliases(passwords map[string][]byte) map[string][]byte {
+	converted := make(map[string][]byte, len(passwords))
+
+	for alias, password := range passwords {
+		converted[ks.convertAlias(alias)] = password
+	}
+
+	return converted
+}
+
+func convertPrivateKeyEntry(pke PrivateKeyEntry, from, to int, password []byte) (PrivateKeyEntry, error) {
+	for i, cert := range pke.CertificateChain {
+		if err := checkCertificateType(to, cert); err != nil {
+			return PrivateKeyEntry{}, fmt.Errorf("check %d certificate: %w", i, err)
+		}
+	}
+
+	if from == to {
+		return pke, nil
+	}
+
+	if password == nil {
+		return PrivateKeyEntry{}, ErrMissingPassword
+	}
+
+	plainKey, err := decrypt(pke.encryptedPrivateKey, password)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer zeroing(plainKey)
+
+	epk, err := encryptPrivateKey(to, plainKey, password)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("encrypt private key: %w", err)
+	}
+
+	pke.encryptedPrivateKey = epk
+
+	return pke, nil
+}
+
+// checkCertificateType reports certificates which can't be kept by PKCS12 keystore.
+func checkCertificateType(storeType int, cert Certificate) error {
+	if storeType == PKCS12StoreType && !isX509Certificate(cert) {
+		return fmt.Errorf("got certificate of type %s: %w", cert.Type, ErrUnsupportedEntryType)
+	}
+
+	return nil
+}
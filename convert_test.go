@@ -0,0 +1,98 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	keyPassword := []byte("keypassword")
+
+	f, err := os.Open("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatalf("open test data keystore file: %s", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatalf("close test data keystore file: %s", err)
+		}
+	}()
+
+	jks := New()
+	if err := jks.Load(f, password); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPKE, err := jks.GetPrivateKeyEntry("alias", keyPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, storeType := range []int{JCEKSStoreType, PKCS12StoreType} {
+		// the password alias is converted as the keystore aliases are
+		converted, err := Convert(jks, storeType, map[string][]byte{"ALIAS": keyPassword})
+		if err != nil {
+			t.Fatalf("convert to %d: %s", storeType, err)
+		}
+
+		var buf bytes.Buffer
+		if err := converted.Store(&buf, password); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := New()
+		if err := loaded.Load(&buf, password); err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded.StoreType() != storeType {
+			t.Errorf("unexpected store type: %d", loaded.StoreType())
+		}
+
+		actualPKE, err := loaded.GetPrivateKeyEntry("alias", keyPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(actualPKE, expectedPKE) {
+			t.Errorf("private key entries not equal after conversion to %d", storeType)
+		}
+	}
+
+	if _, err := Convert(jks, PKCS12StoreType, nil); !errors.Is(err, ErrMissingPassword) {
+		t.Errorf("unexpected error without password: %v", err)
+	}
+}
+
+func TestConvertUnsupportedEntry(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	jceks := New(WithStoreType(JCEKSStoreType))
+
+	err := jceks.SetSecurityKeyEntry("ske-alias", SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Convert(jceks, JDKStoreType, nil); !errors.Is(err, ErrUnsupportedEntryType) {
+		t.Errorf("unexpected error converting security key to jks: %v", err)
+	}
+
+	if _, err := Convert(jceks, JCEKSStoreType, nil); err != nil {
+		t.Errorf("unexpected error converting to the same type: %v", err)
+	}
+}
@@ -2,31 +2,95 @@ package keystore
 
 import (
 	"bufio"
-	"errors"
+	"bytes"
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 
 	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
 )
 
 const defaultCertificateType = "X509"
 
+const maxPreallocatedChainLen = 16
+
 type keyStoreDecoder struct {
 	r           io.Reader
 	b           [bufSize]byte
 	md          hash.Hash
 	javaDecoder jserial.Decoder
+	opts        loadOptions
+	br          *bufio.Reader
+	cr          *countingReader
 }
 
 func newKeyStoreDecoder(r io.Reader, md hash.Hash) *keyStoreDecoder {
-	br := bufio.NewReaderSize(r, bufSize)
+	cr := &countingReader{r: r}
+	br := bufio.NewReaderSize(cr, bufSize)
 
 	return &keyStoreDecoder{
 		r:           br,
 		md:          md,
 		javaDecoder: jserial.NewDecoder(br),
+		br:          br,
+		cr:          cr,
+	}
+}
+
+// offset returns number of bytes consumed by the decoder.
+func (ksd *keyStoreDecoder) offset() int64 {
+	return ksd.cr.n - int64(ksd.br.Buffered())
+}
+
+// countingReader counts bytes read from r and records them if rec is set.
+// Reading beyond max bytes fails with ErrLimitExceeded if max is positive.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	rec *bytes.Buffer
+	max int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.max > 0 {
+		if c.n >= c.max {
+			return 0, fmt.Errorf("got object larger than limit: %w", ErrLimitExceeded)
+		}
+
+		if int64(len(p)) > c.max-c.n {
+			p = p[:c.max-c.n]
+		}
+	}
+
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	if c.rec != nil {
+		c.rec.Write(p[:n])
 	}
+
+	return n, err
+}
+
+// record calls read and returns bytes it has consumed from the decoder.
+// The bytes buffered before the call are recorded too, as the buffer is shared with the java decoder.
+func (ksd *keyStoreDecoder) record(read func() error) ([]byte, error) {
+	start := ksd.offset()
+
+	buffered, err := ksd.br.Peek(ksd.br.Buffered())
+	if err != nil {
+		return nil, fmt.Errorf("peek buffered bytes: %w", err)
+	}
+
+	ksd.cr.rec = bytes.NewBuffer(append([]byte(nil), buffered...))
+	defer func() { ksd.cr.rec = nil }()
+
+	if err := read(); err != nil {
+		return nil, err
+	}
+
+	return ksd.cr.rec.Bytes()[:ksd.offset()-start], nil
 }
 
 func (ksd *keyStoreDecoder) readUint16() (uint16, error) {
@@ -90,7 +154,7 @@ func (ksd *keyStoreDecoder) readString() (string, error) {
 		return "", fmt.Errorf("read body: %w", err)
 	}
 
-	return string(strBody), nil
+	return decodeModifiedUTF8(strBody)
 }
 
 func (ksd *keyStoreDecoder) readCertificate(version uint32) (Certificate, error) {
@@ -107,7 +171,7 @@ func (ksd *keyStoreDecoder) readCertificate(version uint32) (Certificate, error)
 
 		certType = readCertType
 	default:
-		return Certificate{}, errors.New("got unknown version")
+		return Certificate{}, fmt.Errorf("got version %d: %w", version, ErrUnknownVersion)
 	}
 
 	certLen, err := ksd.readUint32()
@@ -115,6 +179,10 @@ func (ksd *keyStoreDecoder) readCertificate(version uint32) (Certificate, error)
 		return Certificate{}, fmt.Errorf("read length: %w", err)
 	}
 
+	if err := checkLimit("certificate size", uint64(certLen), int64(ksd.opts.maxCertificateSize)); err != nil {
+		return Certificate{}, err
+	}
+
 	certContent, err := ksd.readBytes(certLen)
 	if err != nil {
 		return Certificate{}, fmt.Errorf("read content: %w", err)
@@ -139,17 +207,32 @@ func (ksd *keyStoreDecoder) readPrivateKeyEntry(version uint32) (PrivateKeyEntry
 		return PrivateKeyEntry{}, fmt.Errorf("read length: %w", err)
 	}
 
+	if err := checkLimit("private key size", uint64(length), int64(ksd.opts.maxKeySize)); err != nil {
+		return PrivateKeyEntry{}, err
+	}
+
 	encryptedPrivateKey, err := ksd.readBytes(length)
 	if err != nil {
 		return PrivateKeyEntry{}, fmt.Errorf("read encrypted private key: %w", err)
 	}
 
+	iterations := keyIterations(encryptedPrivateKey)
+	if err := checkIterationLimit("private key", iterations, ksd.opts.maxIterations); err != nil {
+		return PrivateKeyEntry{}, err
+	}
+
 	certNum, err := ksd.readUint32()
 	if err != nil {
 		return PrivateKeyEntry{}, fmt.Errorf("read number of certificates: %w", err)
 	}
 
-	chain := make([]Certificate, 0, certNum)
+	// certNum comes from the keystore, so it is not trusted to preallocate the chain.
+	chainCap := certNum
+	if chainCap > maxPreallocatedChainLen {
+		chainCap = maxPreallocatedChainLen
+	}
+
+	chain := make([]Certificate, 0, chainCap)
 
 	for i := uint32(0); i < certNum; i++ {
 		cert, err := ksd.readCertificate(version)
@@ -181,13 +264,36 @@ func (ksd *keyStoreDecoder) readSecurityKeyEntry(_ uint32) (SecurityKeyEntry, er
 		CreationTime: creationDateTime,
 	}
 	esk := &jserial.EncryptedSecurityKey{}
-	err = ksd.javaDecoder.Decode(esk)
 
+	if limit := int64(ksd.opts.maxKeySize); limit > 0 {
+		// The java decoder allocates memory for the object as it is read, so reading is stopped
+		// after the limit and the bytes which can be buffered after the object.
+		ksd.cr.max = ksd.offset() + limit + bufSize
+		defer func() { ksd.cr.max = 0 }()
+	}
+
+	sealed, err := ksd.record(func() error { return ksd.javaDecoder.Decode(esk) })
 	if err != nil {
+		// the java decoder doesn't keep the cause of the failure
+		if ksd.cr.max > 0 && ksd.cr.n >= ksd.cr.max {
+			return SecurityKeyEntry{}, fmt.Errorf("got sealed security key larger than limit %d: %w",
+				ksd.opts.maxKeySize, ErrLimitExceeded)
+		}
+
 		return SecurityKeyEntry{}, fmt.Errorf("deserialize security key: %w", err)
 	}
 
+	if err := checkLimit("sealed security key size", uint64(len(sealed)), int64(ksd.opts.maxKeySize)); err != nil {
+		return SecurityKeyEntry{}, err
+	}
+
+	iterations := pbeParamsIterations(esk.EncodedParams)
+	if err := checkIterationLimit("security key", iterations, ksd.opts.maxIterations); err != nil {
+		return SecurityKeyEntry{}, err
+	}
+
 	securityKeyEntry.EncryptedSecurityKey = *esk
+	securityKeyEntry.sealed = &sealedSecurityKey{serialized: sealed, encrypted: *esk}
 
 	return securityKeyEntry, nil
 }
@@ -212,7 +318,8 @@ func (ksd *keyStoreDecoder) readTrustedCertificateEntry(version uint32) (Trusted
 	return trustedCertificateEntry, nil
 }
 
-func (ksd *keyStoreDecoder) readEntry(version uint32) (string, interface{}, error) {
+// readEntry reads the next entry, remaining is a number of entries left including it.
+func (ksd *keyStoreDecoder) readEntry(version, remaining uint32) (string, interface{}, error) {
 	tag, err := ksd.readUint32()
 	if err != nil {
 		return "", nil, fmt.Errorf("read tag: %w", err)
@@ -227,25 +334,62 @@ func (ksd *keyStoreDecoder) readEntry(version uint32) (string, interface{}, erro
 	case privateKeyTag:
 		entry, err := ksd.readPrivateKeyEntry(version)
 		if err != nil {
-			return "", nil, fmt.Errorf("read private key entry: %w", err)
+			return alias, nil, fmt.Errorf("read private key entry: %w", err)
 		}
 
 		return alias, entry, nil
 	case trustedCertificateTag:
 		entry, err := ksd.readTrustedCertificateEntry(version)
 		if err != nil {
-			return "", nil, fmt.Errorf("read trusted certificate entry: %w", err)
+			return alias, nil, fmt.Errorf("read trusted certificate entry: %w", err)
 		}
 
 		return alias, entry, nil
 	case securityKeyTag:
 		entry, err := ksd.readSecurityKeyEntry(version)
 		if err != nil {
-			return "", nil, fmt.Errorf("read security key entry: %w", err)
+			return alias, nil, fmt.Errorf("read security key entry: %w", err)
 		}
 
 		return alias, entry, nil
 	default:
-		return "", nil, errors.New("got unknown entry tag")
+		trailer, err := ksd.readRawTrailer(tag, alias, version, remaining)
+		if err != nil {
+			return alias, nil, fmt.Errorf("read raw trailer with tag %d: %w", tag, err)
+		}
+
+		return alias, trailer, nil
+	}
+}
+
+// readRawTrailer reads the rest of the keystore as the entry can't be delimited without knowing its format.
+// Reading stops as soon as the payload exceeds the trailer size limit.
+func (ksd *keyStoreDecoder) readRawTrailer(tag uint32, alias string, version, remaining uint32) (RawTrailer, error) {
+	creationTimeStamp, err := ksd.readUint64()
+	if err != nil {
+		return RawTrailer{}, fmt.Errorf("read creation timestamp: %w", err)
+	}
+
+	r := ksd.r
+	if ksd.opts.maxTrailerSize > 0 {
+		r = io.LimitReader(ksd.r, int64(ksd.opts.maxTrailerSize)+1)
+	}
+
+	payload, err := ioutil.ReadAll(r)
+	if err != nil {
+		return RawTrailer{}, fmt.Errorf("read payload: %w", err)
 	}
+
+	if err := checkLimit("raw trailer size", uint64(len(payload)), int64(ksd.opts.maxTrailerSize)); err != nil {
+		return RawTrailer{}, err
+	}
+
+	return RawTrailer{
+		Tag:          tag,
+		Alias:        alias,
+		CreationTime: millisecondsToTime(int64(creationTimeStamp)),
+		Payload:      payload,
+		EntryNum:     remaining,
+		version:      version,
+	}, nil
 }
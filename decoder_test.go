@@ -5,7 +5,6 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -389,7 +388,7 @@ func TestReadCertificate(t *testing.T) {
 		table = append(table, readCertificateItem{
 			input:   nil,
 			version: 3,
-			err:     errors.New("got unknown version"),
+			err:     fmt.Errorf("got version %d: %w", 3, ErrUnknownVersion),
 			hash:    sha1.Sum(nil),
 		})
 		table = append(table, func() readCertificateItem {
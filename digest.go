@@ -3,6 +3,7 @@ package keystore
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"hash"
@@ -19,6 +20,7 @@ var (
 type Reader struct {
 	r        *bufio.Reader
 	md       hash.Hash
+	legacyMD hash.Hash
 	signHash []byte
 }
 
@@ -40,6 +42,12 @@ func (d *Reader) Read(b []byte) (n int, err error) {
 		return n, fmt.Errorf("digest reader read error: %w", err)
 	}
 
+	if d.legacyMD != nil {
+		if _, err := d.legacyMD.Write(b[:n]); err != nil {
+			return n, fmt.Errorf("legacy digest reader read error: %w", err)
+		}
+	}
+
 	return
 }
 
@@ -66,7 +74,51 @@ func (d *Reader) VerifySign() (ok bool, err error) {
 		err = ErrNonCompleteRead
 	}
 
-	ok = bytes.Equal(d.md.Sum(nil), d.signHash)
+	ok = bytes.Equal(d.md.Sum(nil), d.signHash) || d.legacyMD != nil && bytes.Equal(d.legacyMD.Sum(nil), d.signHash)
 
 	return
 }
+
+// newKeyStoreDigest returns SHA-1 digest of JKS or JCEKS keystore initialized with the password
+// and the whitener message as Java does.
+func newKeyStoreDigest(password []byte) (hash.Hash, error) {
+	passwordBytes := passwordBytes(password)
+	defer zeroing(passwordBytes)
+
+	return initKeyStoreDigest(passwordBytes)
+}
+
+// newVerifyingReader returns Reader verifying the keystore signature made with the password
+// or with its legacy encoding, so keystores with non-ASCII passwords written by older versions can be read.
+func newVerifyingReader(r io.Reader, password []byte) (*Reader, error) {
+	md, err := newKeyStoreDigest(password)
+	if err != nil {
+		return nil, err
+	}
+
+	signReader := NewReader(r, md)
+
+	if legacyBytes := legacyPasswordBytes(password); legacyBytes != nil {
+		defer zeroing(legacyBytes)
+
+		if signReader.legacyMD, err = initKeyStoreDigest(legacyBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return signReader, nil
+}
+
+func initKeyStoreDigest(passwordBytes []byte) (hash.Hash, error) {
+	md := sha1.New()
+
+	if _, err := md.Write(passwordBytes); err != nil {
+		return nil, fmt.Errorf("update digest with password: %w", err)
+	}
+
+	if _, err := md.Write(whitenerMessage); err != nil {
+		return nil, fmt.Errorf("update digest with whitener message: %w", err)
+	}
+
+	return md, nil
+}
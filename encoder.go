@@ -1,16 +1,21 @@
 package keystore
 
 import (
+	"bytes"
 	"fmt"
 	"hash"
 	"io"
 	"math"
+	"reflect"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
 )
 
 type keyStoreEncoder struct {
-	w  io.Writer
-	b  [bufSize]byte
-	md hash.Hash
+	w       io.Writer
+	b       [bufSize]byte
+	md      hash.Hash
+	version uint32
 }
 
 func (kse *keyStoreEncoder) writeUint16(value uint16) error {
@@ -74,7 +79,9 @@ func (kse *keyStoreEncoder) writeBytes(value []byte) error {
 }
 
 func (kse *keyStoreEncoder) writeString(value string) error {
-	strLen := len(value)
+	encoded := encodeModifiedUTF8(value)
+
+	strLen := len(encoded)
 	if strLen > math.MaxUint16 {
 		return fmt.Errorf("got string %d bytes long, max length is %d", strLen, math.MaxUint16)
 	}
@@ -83,7 +90,7 @@ func (kse *keyStoreEncoder) writeString(value string) error {
 		return fmt.Errorf("write length: %w", err)
 	}
 
-	if err := kse.writeBytes([]byte(value)); err != nil {
+	if err := kse.writeBytes(encoded); err != nil {
 		return fmt.Errorf("write body: %w", err)
 	}
 
@@ -91,7 +98,12 @@ func (kse *keyStoreEncoder) writeString(value string) error {
 }
 
 func (kse *keyStoreEncoder) writeCertificate(cert Certificate) error {
-	if err := kse.writeString(cert.Type); err != nil {
+	// version 1 keystores have no certificate type, all certificates are X.509
+	if kse.version == version01 {
+		if !isX509Certificate(cert) {
+			return fmt.Errorf("got certificate of type %s in version 1 keystore: %w", cert.Type, ErrUnsupportedEntryType)
+		}
+	} else if err := kse.writeString(cert.Type); err != nil {
 		return fmt.Errorf("write type: %w", err)
 	}
 
@@ -175,3 +187,62 @@ func (kse *keyStoreEncoder) writeTrustedCertificateEntry(alias string, tce Trust
 
 	return nil
 }
+
+func (kse *keyStoreEncoder) writeSecurityKeyEntry(alias string, ske SecurityKeyEntry) error {
+	if err := kse.writeUint32(securityKeyTag); err != nil {
+		return fmt.Errorf("write tag: %w", err)
+	}
+
+	if err := kse.writeString(alias); err != nil {
+		return fmt.Errorf("write alias: %w", err)
+	}
+
+	if err := kse.writeUint64(uint64(timeToMilliseconds(ske.CreationTime))); err != nil {
+		return fmt.Errorf("write creation timestamp: %w", err)
+	}
+
+	// The sealed object read by Load is written as is unless the encrypted key has been changed.
+	if ske.sealed != nil && reflect.DeepEqual(ske.sealed.encrypted, ske.EncryptedSecurityKey) {
+		if err := kse.writeBytes(ske.sealed.serialized); err != nil {
+			return fmt.Errorf("write sealed security key: %w", err)
+		}
+
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := jserial.NewEncoder(&buf).Encode(ske.EncryptedSecurityKey); err != nil {
+		return fmt.Errorf("serialize security key: %w", err)
+	}
+
+	if err := kse.writeBytes(buf.Bytes()); err != nil {
+		return fmt.Errorf("write sealed security key: %w", err)
+	}
+
+	return nil
+}
+
+func (kse *keyStoreEncoder) writeRawTrailer(trailer RawTrailer) error {
+	if trailer.version != kse.version {
+		return fmt.Errorf("got raw trailer of version %d keystore, writing version %d: %w",
+			trailer.version, kse.version, ErrUnsupportedEntryType)
+	}
+
+	if err := kse.writeUint32(trailer.Tag); err != nil {
+		return fmt.Errorf("write tag: %w", err)
+	}
+
+	if err := kse.writeString(trailer.Alias); err != nil {
+		return fmt.Errorf("write alias: %w", err)
+	}
+
+	if err := kse.writeUint64(uint64(timeToMilliseconds(trailer.CreationTime))); err != nil {
+		return fmt.Errorf("write creation timestamp: %w", err)
+	}
+
+	if err := kse.writeBytes(trailer.Payload); err != nil {
+		return fmt.Errorf("write payload: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
+)
+
+func TestTypedErrors(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(data), []byte("wrongpassword")); !errors.Is(err, ErrInvalidDigest) {
+		t.Errorf("unexpected error for wrong password: %v", err)
+	}
+
+	var corrupt ErrCorrupt
+
+	err = ks.Load(bytes.NewReader(data[:100]), []byte("password"))
+	if !errors.As(err, &corrupt) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected error for truncated keystore: %v", err)
+	}
+
+	if corrupt.Entry != 0 || corrupt.Alias != "alias" || corrupt.Offset != 12 {
+		t.Errorf("unexpected corrupt error details: %+v", corrupt)
+	}
+
+	eof := ErrCorrupt{Err: fmt.Errorf("read tag: %w", io.EOF)}
+	if errors.Is(eof, io.EOF) || !errors.Is(eof, io.ErrUnexpectedEOF) {
+		t.Errorf("corrupt error must not be taken for the end of keystore: %v", eof)
+	}
+
+	// keystores shorter than the magic
+	for _, n := range []int{0, 1, 3} {
+		err = ks.Load(bytes.NewReader(data[:n]), []byte("password"))
+		if !errors.As(err, &corrupt) || !errors.Is(err, io.ErrUnexpectedEOF) || corrupt.Entry != -1 {
+			t.Errorf("unexpected error loading %d bytes: %v", n, err)
+		}
+
+		if _, err := NewDecoder(bytes.NewReader(data[:n]), []byte("password")); !errors.As(err, &corrupt) {
+			t.Errorf("unexpected error decoding %d bytes: %v", n, err)
+		}
+
+		if _, err := Verify(bytes.NewReader(data[:n]), []byte("password")); !errors.As(err, &corrupt) {
+			t.Errorf("unexpected error verifying %d bytes: %v", n, err)
+		}
+	}
+
+	err = ks.Load(bytes.NewReader(data), []byte("password"), WithMaxCertificateSize(16))
+	if !errors.Is(err, ErrLimitExceeded) || errors.As(err, &corrupt) {
+		t.Errorf("unexpected error for exceeded limit: %v", err)
+	}
+
+	unknownVersion := append([]byte(nil), data...)
+	unknownVersion[7] = 3
+
+	err = ks.Load(bytes.NewReader(unknownVersion), []byte("password"))
+	if !errors.As(err, &corrupt) || !errors.Is(err, ErrUnknownVersion) || corrupt.Entry != -1 {
+		t.Errorf("unexpected error for unknown version: %v", err)
+	}
+
+	oid := asn1.ObjectIdentifier{1, 2, 3, 4}
+
+	epk, err := asn1.Marshal(keyInfo{Algo: pkix.AlgorithmIdentifier{Algorithm: oid}, PrivateKey: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unsupported ErrUnsupportedAlgorithm
+
+	_, err = PrivateKeyEntry{encryptedPrivateKey: epk}.Decrypt([]byte("password"))
+	if !errors.As(err, &unsupported) || !unsupported.OID.Equal(oid) {
+		t.Errorf("unexpected error for unsupported algorithm: %v", err)
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jks := New()
+	if err := jks.Load(bytes.NewReader(data), []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jks.GetPrivateKeyEntry("alias", []byte("wrongpassword")); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("unexpected error for wrong jks key password: %v", err)
+	}
+
+	password := []byte("password")
+
+	jceks := New(WithStoreType(JCEKSStoreType))
+
+	if err := jceks.SetPrivateKeyEntry("pke", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jceks.SetSecurityKeyEntry("ske", SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	// Some of wrong passwords give valid padding, they must be caught by the key format checks.
+	for i := 0; i < 100; i++ {
+		wrongPassword := []byte(fmt.Sprintf("wrongpassword%d", i))
+
+		if _, err := jceks.GetPrivateKeyEntry("pke", wrongPassword); !errors.Is(err, ErrWrongPassword) {
+			t.Fatalf("unexpected error for wrong jceks key password %q: %v", wrongPassword, err)
+		}
+
+		if _, err := jceks.GetSecurityKeyEntry("ske", wrongPassword); !errors.Is(err, ErrWrongPassword) {
+			t.Fatalf("unexpected error for wrong security key password %q: %v", wrongPassword, err)
+		}
+	}
+}
+
+func TestMalformedKey(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	// JKS key passes the integrity check, so it is decrypted with the right password
+	notPKCS8, err := encrypt(rand.Reader, []byte("not a pkcs#8 key"), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, epk := range map[string][]byte{"not pkcs#8": notPKCS8, "not der": []byte("not der")} {
+		_, err := PrivateKeyEntry{encryptedPrivateKey: epk}.Decrypt(password)
+		if !errors.Is(err, ErrMalformedKey) || errors.Is(err, ErrWrongPassword) {
+			t.Errorf("unexpected error for %s private key: %v", name, err)
+		}
+	}
+
+	malformed := append(append([]byte(nil), javaStreamHeader...), "not an object"...)
+	esk, err := encryptSecurityKey(rand.Reader, malformed, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ske := SecurityKeyEntry{EncryptedSecurityKey: esk}
+
+	if _, err := ske.Decrypt(password); !errors.Is(err, ErrMalformedKey) || errors.Is(err, ErrWrongPassword) {
+		t.Errorf("unexpected error for malformed security key: %v", err)
+	}
+
+	// a serialized object which is neither KeyRep nor SecretKeySpec is not a key
+	var sealed bytes.Buffer
+	if err := jserial.NewEncoder(&sealed).Encode(esk); err != nil {
+		t.Fatal(err)
+	}
+
+	if ske.EncryptedSecurityKey, err = encryptSecurityKey(rand.Reader, sealed.Bytes(), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ske.Decrypt(password); !errors.Is(err, ErrMalformedKey) || errors.Is(err, ErrWrongPassword) {
+		t.Errorf("unexpected error for security key of other class: %v", err)
+	}
+}
+
+func TestShortSalt(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	shortSalt := pbeParams{Salt: []byte{1, 2}, Iterations: 1}.Encode()
+
+	epk, err := asn1.Marshal(keyInfo{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  jcePrivateKeyAlgorithmOid,
+			Parameters: asn1.RawValue{FullBytes: shortSalt},
+		},
+		PrivateKey: make([]byte, 8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	ks.setEntry("pke", PrivateKeyEntry{
+		encryptedPrivateKey: epk,
+		CertificateChain:    []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	})
+	ks.setEntry("ske", SecurityKeyEntry{EncryptedSecurityKey: jserial.EncryptedSecurityKey{
+		EncodedParams:    shortSalt,
+		EncryptedContent: make([]byte, 8),
+		ParamsAlg:        "PBEWithMD5AndTripleDES",
+		SealAlg:          "PBEWithMD5AndTripleDES",
+	}})
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loaded.GetPrivateKeyEntry("pke", password); !errors.Is(err, ErrMalformedKey) {
+		t.Errorf("unexpected error for private key with short salt: %v", err)
+	}
+
+	if _, err := loaded.GetSecurityKeyEntry("ske", password); !errors.Is(err, ErrMalformedKey) {
+		t.Errorf("unexpected error for security key with short salt: %v", err)
+	}
+}
@@ -2,48 +2,24 @@ package main
 
 import (
 	"log"
-	"os"
 	"reflect"
 
 	"github.com/pavel-v-chernykh/keystore-go/v4"
 )
 
 func readKeyStore(filename string, password []byte) keystore.KeyStore {
-	f, err := os.Open(filename)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Fatal(err)
-		}
-	}()
-
 	keyStore := keystore.New()
-	if err := keyStore.Load(f, password); err != nil {
-		log.Fatal(err) // nolint: gocritic
+	if err := keyStore.LoadFile(filename, password); err != nil {
+		log.Fatal(err)
 	}
 
 	return keyStore
 }
 
 func writeKeyStore(keyStore keystore.KeyStore, filename string, password []byte) {
-	f, err := os.Create(filename)
-	if err != nil {
+	if err := keyStore.StoreFile(filename, password); err != nil {
 		log.Fatal(err)
 	}
-
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	err = keyStore.Store(f, password)
-	if err != nil {
-		log.Fatal(err) // nolint: gocritic
-	}
 }
 
 func zeroing(s []byte) {
@@ -0,0 +1,179 @@
+package keystore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultFileMode os.FileMode = 0o600
+	backupSuffix                = ".bak"
+)
+
+type fileOptions struct {
+	mode   os.FileMode
+	backup bool
+}
+
+// FileOption configures StoreFile.
+type FileOption func(*fileOptions)
+
+// WithFileMode sets permissions of the keystore file. By default permissions of the existing file are kept
+// and new files are created with 0600.
+func WithFileMode(mode os.FileMode) FileOption {
+	return func(o *fileOptions) { o.mode = mode }
+}
+
+// WithBackup keeps the previous version of the keystore file next to it with .bak suffix.
+func WithBackup() FileOption { return func(o *fileOptions) { o.backup = true } }
+
+// LoadFile reads keystore from the file by path, see Load.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks *KeyStore) LoadFile(path string, password []byte, options ...LoadOption) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open keystore file: %w", err)
+	}
+
+	defer f.Close()
+
+	return ks.Load(f, password, options...)
+}
+
+// StoreFile writes keystore to the file by path atomically. The keystore is written to a temporary file
+// in the same directory which is synced and renamed to path, so the file is never left truncated.
+// If path is a symbolic link the file it points to is replaced and the link is kept.
+// Owner of the existing file is kept, StoreFile fails if it can't be set on the new version.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) StoreFile(path string, password []byte, options ...FileOption) error {
+	opts := fileOptions{}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+
+	switch {
+	case err == nil:
+		path = resolved
+	case !os.IsNotExist(err):
+		return fmt.Errorf("resolve keystore file: %w", err)
+	}
+
+	info, err := os.Stat(path)
+
+	switch {
+	case err == nil:
+		if opts.mode == 0 {
+			opts.mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		opts.backup = false
+	default:
+		return fmt.Errorf("stat keystore file: %w", err)
+	}
+
+	if opts.mode == 0 {
+		opts.mode = defaultFileMode
+	}
+
+	if opts.backup {
+		if err := backupFile(path, opts.mode, info); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, opts.mode, info, func(w io.Writer) error {
+		return ks.Store(w, password)
+	})
+}
+
+func backupFile(path string, mode os.FileMode, owner os.FileInfo) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open keystore file: %w", err)
+	}
+
+	defer src.Close()
+
+	return writeFileAtomic(path+backupSuffix, mode, owner, func(w io.Writer) error {
+		if _, err := io.Copy(w, src); err != nil {
+			return fmt.Errorf("copy keystore file: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// writeFileAtomic writes file by path with write function via synced temporary file and rename.
+// The file gets owner of the file described by owner unless it is nil.
+func writeFileAtomic(path string, mode os.FileMode, owner os.FileInfo, write func(w io.Writer) error) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	bw := bufio.NewWriter(tmp)
+
+	if err := write(bw); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("change mode of temporary file: %w", err)
+	}
+
+	if owner != nil {
+		if err := chownLike(tmp, owner); err != nil {
+			return fmt.Errorf("change owner of temporary file: %w", err)
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir makes the rename durable, errors are ignored as some platforms can't sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,170 @@
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreFile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	password := []byte("password")
+	path := filepath.Join(dir, "keystore.jks")
+
+	ks := New()
+	if err := ks.LoadFile("./testdata/keystore_keypass.jks", password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(path, password, WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("unexpected mode of new file %v", info.Mode())
+	}
+
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup of not existing file is created: %v", err)
+	}
+
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetTrustedCertificateEntry("ca", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(path, password, WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ioutil.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(backup, original) {
+		t.Error("backup differs from the previous version")
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("mode of existing file is not kept: %v, %v", info.Mode(), err)
+	}
+
+	loaded := New()
+	if err := loaded.LoadFile(path, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.IsTrustedCertificateEntry("ca") || !loaded.IsPrivateKeyEntry("alias") {
+		t.Error("unexpected entries of stored keystore")
+	}
+
+	if err := ks.StoreFile(path, []byte("short"), WithFileMode(0o644)); err == nil {
+		t.Fatal("store with short password should fail")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Errorf("temporary files are left: %d files in dir", len(files))
+	}
+
+	if err := loaded.LoadFile(path, password); err != nil {
+		t.Errorf("keystore file is damaged by failed store: %v", err)
+	}
+}
+
+func TestStoreFileSymlink(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "target"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	password := []byte("password")
+	target := filepath.Join(dir, "target", "keystore.jks")
+	link := filepath.Join(dir, "keystore.jks")
+
+	ks := New()
+	if err := ks.LoadFile("./testdata/keystore_keypass.jks", password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(target, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join("target", "keystore.jks"), link); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+
+	if err := ks.SetTrustedCertificateEntry("ca", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(link, password, WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symbolic link is replaced: %v", err)
+	}
+
+	loaded := New()
+	if err := loaded.LoadFile(target, password); err != nil || !loaded.IsTrustedCertificateEntry("ca") {
+		t.Errorf("target of symbolic link is not updated: %v", err)
+	}
+
+	if _, err := os.Stat(target + ".bak"); err != nil {
+		t.Errorf("backup is not created next to the target: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Errorf("unexpected files next to symbolic link: %d files in dir", len(files))
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package keystore
+
+import (
+	"os"
+	"syscall"
+)
+
+// chownLike sets owner and group of the file described by info to f if they differ.
+func chownLike(f *os.File, info os.FileInfo) error {
+	want, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	current, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if got, ok := current.Sys().(*syscall.Stat_t); ok && got.Uid == want.Uid && got.Gid == want.Gid {
+		return nil
+	}
+
+	return f.Chown(int(want.Uid), int(want.Gid))
+}
@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestStoreFileOwner(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() != 0 {
+		t.Skip("changing owner of files requires root")
+	}
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	password := []byte("password")
+	path := filepath.Join(dir, "keystore.jks")
+
+	ks := New()
+	if err := ks.LoadFile("./testdata/keystore_keypass.jks", password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(path, password); err != nil {
+		t.Fatal(err)
+	}
+
+	const uid, gid = 1, 2
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.StoreFile(path, password, WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, path + backupSuffix} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stat := info.Sys().(*syscall.Stat_t); stat.Uid != uid || stat.Gid != gid {
+			t.Errorf("owner of %s is not kept: %d:%d", filepath.Base(name), stat.Uid, stat.Gid)
+		}
+	}
+}
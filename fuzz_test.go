@@ -0,0 +1,71 @@
+//go:build go1.18
+// +build go1.18
+
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzLoad checks that malformed keystores and keys of loaded keystores are reported with errors instead of panics.
+// The corpus is built from testdata keystores and a JCEKS keystore with JCE protected keys,
+// run it with `go test -fuzz=FuzzLoad`.
+func FuzzLoad(f *testing.F) {
+	for _, pattern := range []string{"./testdata/*.jks", "./testdata/*.p12"} {
+		names, err := filepath.Glob(pattern)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		for _, name := range names {
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				f.Fatal(err)
+			}
+
+			f.Add(data)
+		}
+	}
+
+	passwords := [][]byte{[]byte("password"), []byte("keypassword")}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	if err := ks.SetPrivateKeyEntry("pke", PrivateKeyEntry{
+		PrivateKey: readPEMBlock(f, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readPEMBlock(f, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+		},
+	}, passwords[0]); err != nil {
+		f.Fatal(err)
+	}
+
+	if err := ks.SetSecurityKeyEntry("ske", SecurityKeyEntry{
+		SecurityKey: []byte("0123456789abcdef"),
+		Algorithm:   "AES",
+	}, passwords[0]); err != nil {
+		f.Fatal(err)
+	}
+
+	var jceks bytes.Buffer
+	if err := ks.Store(&jceks, passwords[0]); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(jceks.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ks := New()
+		_ = ks.Load(bytes.NewReader(data), passwords[0], WithoutIntegrityCheck(),
+			WithMaxEntries(64), WithMaxCertificateSize(1<<16), WithMaxKeySize(1<<16), WithMaxSize(1<<20))
+
+		for _, alias := range ks.Aliases() {
+			for _, password := range passwords {
+				_, _ = ks.GetPrivateKeyEntry(alias, password)
+				_, _ = ks.GetSecurityKeyEntry(alias, password)
+			}
+		}
+	})
+}
@@ -1,5 +1,5 @@
 module github.com/pavel-v-chernykh/keystore-go/v4
 
-go 1.14
+go 1.15
 
 require github.com/jkeys089/jserial v1.0.1
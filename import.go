@@ -0,0 +1,272 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
+)
+
+var ErrAliasExists = errors.New("alias already exists")
+
+// ConflictPolicy defines what Import does with an entry whose alias already exists in the destination keystore.
+type ConflictPolicy int
+
+const (
+	// ConflictFail stops the import with ErrAliasExists.
+	ConflictFail ConflictPolicy = iota
+	// ConflictSkip keeps the destination entry.
+	ConflictSkip
+	// ConflictOverwrite replaces the destination entry.
+	ConflictOverwrite
+	// ConflictRename imports the entry by the alias with the rename suffix and the first free number.
+	ConflictRename
+)
+
+const defaultRenameSuffix = "_"
+
+// ImportAction describes what Import has done with an entry.
+type ImportAction int
+
+const (
+	ImportAdded ImportAction = iota
+	ImportOverwritten
+	ImportRenamed
+	ImportSkipped
+)
+
+func (a ImportAction) String() string {
+	switch a {
+	case ImportAdded:
+		return "added"
+	case ImportOverwritten:
+		return "overwritten"
+	case ImportRenamed:
+		return "renamed"
+	case ImportSkipped:
+		return "skipped"
+	default:
+		return "unknown action " + strconv.Itoa(int(a))
+	}
+}
+
+// ImportResult is a report of importing an entry. DestAlias is the alias of the entry in the destination keystore,
+// Reencrypted is true if the key has been decrypted and encrypted again for the destination keystore type.
+type ImportResult struct {
+	Alias       string
+	DestAlias   string
+	Action      ImportAction
+	Reencrypted bool
+}
+
+type importOptions struct {
+	policy       ConflictPolicy
+	renameSuffix string
+	aliases      []string
+	passwords    map[string][]byte
+}
+
+// ImportOption configures Import.
+type ImportOption func(*importOptions)
+
+// WithConflictPolicy sets policy for aliases existing in the destination keystore, the default is ConflictFail.
+func WithConflictPolicy(policy ConflictPolicy) ImportOption {
+	return func(o *importOptions) { o.policy = policy }
+}
+
+// WithRenameSuffix sets suffix of aliases renamed with ConflictRename policy, the default is "_",
+// e.g. "alias" is renamed to "alias_1" or "alias_2" if "alias_1" exists too.
+func WithRenameSuffix(suffix string) ImportOption {
+	return func(o *importOptions) { o.renameSuffix = suffix }
+}
+
+// WithImportAliases limits the import to entries by the aliases.
+func WithImportAliases(aliases ...string) ImportOption {
+	return func(o *importOptions) { o.aliases = aliases }
+}
+
+// WithImportPasswords sets passwords of private keys by their aliases in the source keystore,
+// the aliases are converted as the source keystore converts them, so they may be in the original case.
+// The passwords are needed only if the key protection of the source keystore type can't be read by
+// the destination one, the keys are encrypted again with the same passwords then.
+func WithImportPasswords(passwords map[string][]byte) ImportOption {
+	return func(o *importOptions) { o.passwords = passwords }
+}
+
+// Import copies entries from src into the keystore like keytool -importkeystore does.
+// Encrypted keys are copied as is if the destination keystore type can read their protection,
+// otherwise they are decrypted with the passwords set by WithImportPasswords and encrypted again.
+// Either all entries are imported or, on error, the keystore is not changed.
+// The raw trailer of src is not imported, aliases which may be used in the raw trailer of the keystore
+// are conflicts which can be skipped or renamed, but not overwritten.
+// The returned report lists entries in the order they are processed, up to the failed one on error.
+// It is strongly recommended to fill password slices with zero after usage.
+func (ks KeyStore) Import(src KeyStore, options ...ImportOption) ([]ImportResult, error) {
+	opts := importOptions{renameSuffix: defaultRenameSuffix}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	aliases := opts.aliases
+	if aliases == nil {
+		aliases = src.storeAliases()
+	}
+
+	passwords := src.convertPasswordAliases(opts.passwords)
+
+	type importedEntry struct {
+		alias string
+		entry interface{}
+	}
+
+	var (
+		report   = make([]ImportResult, 0, len(aliases))
+		imported = make([]importedEntry, 0, len(aliases))
+		planned  = make(map[string]bool, len(aliases))
+		seen     = make(map[string]bool, len(aliases))
+	)
+
+	for _, alias := range aliases {
+		srcAlias := src.convertAlias(alias)
+
+		// the same entry may be requested by several aliases, e.g. in different cases
+		if seen[srcAlias] {
+			continue
+		}
+
+		seen[srcAlias] = true
+
+		e, ok := src.m[srcAlias]
+		if !ok {
+			return report, fmt.Errorf("import entry %q: %w", alias, ErrEntryNotFound)
+		}
+
+		result := ImportResult{Alias: srcAlias, DestAlias: ks.convertAlias(srcAlias), Action: ImportAdded}
+
+		hidden := ks.checkAlias(result.DestAlias)
+
+		if _, exists := ks.m[result.DestAlias]; exists || planned[result.DestAlias] || hidden != nil {
+			switch {
+			case opts.policy == ConflictSkip:
+				result.Action = ImportSkipped
+				report = append(report, result)
+
+				continue
+			case opts.policy == ConflictOverwrite && hidden == nil:
+				result.Action = ImportOverwritten
+			case opts.policy == ConflictRename:
+				result.Action = ImportRenamed
+				result.DestAlias = ks.freeAlias(result.DestAlias+opts.renameSuffix, planned)
+			case hidden != nil:
+				return report, fmt.Errorf("import entry %q: %w", srcAlias, hidden)
+			default:
+				return report, fmt.Errorf("import entry %q: %w", srcAlias, ErrAliasExists)
+			}
+		}
+
+		entry, reencrypted, err := importEntry(e, src.storeType, ks.storeType, passwords[srcAlias])
+		if err != nil {
+			return report, fmt.Errorf("import entry %q: %w", srcAlias, err)
+		}
+
+		result.Reencrypted = reencrypted
+		planned[result.DestAlias] = true
+		report = append(report, result)
+		imported = append(imported, importedEntry{alias: result.DestAlias, entry: entry})
+	}
+
+	for _, ie := range imported {
+		ks.setEntry(ie.alias, ie.entry)
+	}
+
+	return report, nil
+}
+
+// freeAlias returns prefix followed by the first number which gives an alias not used by the keystore,
+// the alias is converted the same way as in setters.
+func (ks KeyStore) freeAlias(prefix string, planned map[string]bool) string {
+	for i := 1; ; i++ {
+		alias := ks.convertAlias(prefix + strconv.Itoa(i))
+
+		if _, exists := ks.m[alias]; !exists && !planned[alias] && ks.checkAlias(alias) == nil {
+			return alias
+		}
+	}
+}
+
+// importEntry returns the entry suitable for the destination keystore type and true if its key has been re-encrypted.
+// The entry doesn't share slices with the source one.
+func importEntry(e interface{}, from, to int, password []byte) (interface{}, bool, error) {
+	switch typedEntry := e.(type) {
+	case PrivateKeyEntry:
+		typedEntry.encryptedPrivateKey = append([]byte(nil), typedEntry.encryptedPrivateKey...)
+		typedEntry.CertificateChain = copyCertificates(typedEntry.CertificateChain)
+
+		if !canReadKeyProtection(from, to) {
+			pke, err := convertPrivateKeyEntry(typedEntry, from, to, password)
+
+			return pke, true, err
+		}
+
+		for i, cert := range typedEntry.CertificateChain {
+			if err := checkCertificateType(to, cert); err != nil {
+				return nil, false, fmt.Errorf("check %d certificate: %w", i, err)
+			}
+		}
+
+		return typedEntry, false, nil
+	case TrustedCertificateEntry:
+		if err := checkCertificateType(to, typedEntry.Certificate); err != nil {
+			return nil, false, err
+		}
+
+		typedEntry.Certificate.Content = append([]byte(nil), typedEntry.Certificate.Content...)
+
+		return typedEntry, false, nil
+	case SecurityKeyEntry:
+		if to != JCEKSStoreType {
+			return nil, false, fmt.Errorf("import security key entry: %w", ErrUnsupportedEntryType)
+		}
+
+		typedEntry.EncryptedSecurityKey = copyEncryptedSecurityKey(typedEntry.EncryptedSecurityKey)
+
+		if typedEntry.sealed != nil {
+			typedEntry.sealed = &sealedSecurityKey{
+				serialized: append([]byte(nil), typedEntry.sealed.serialized...),
+				encrypted:  copyEncryptedSecurityKey(typedEntry.sealed.encrypted),
+			}
+		}
+
+		return typedEntry, false, nil
+	default:
+		return nil, false, errors.New("got invalid entry")
+	}
+}
+
+func copyEncryptedSecurityKey(esk jserial.EncryptedSecurityKey) jserial.EncryptedSecurityKey {
+	esk.EncodedParams = append([]byte(nil), esk.EncodedParams...)
+	esk.EncryptedContent = append([]byte(nil), esk.EncryptedContent...)
+
+	return esk
+}
+
+func copyCertificates(certs []Certificate) []Certificate {
+	if certs == nil {
+		return nil
+	}
+
+	result := make([]Certificate, len(certs))
+	for i, cert := range certs {
+		result[i] = Certificate{Type: cert.Type, Content: append([]byte(nil), cert.Content...)}
+	}
+
+	return result
+}
+
+// canReadKeyProtection reports whether keystore of type to can read private keys protected by keystore of type from.
+// JCEKS keystores read keys protected by JKS ones as Java's JCEKS key protector does.
+func canReadKeyProtection(from, to int) bool {
+	return from == to || (from == JDKStoreType && to == JCEKSStoreType)
+}
@@ -0,0 +1,294 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := New()
+	if err := src.Load(bytes.NewReader(data), []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	tce := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	if err := src.SetTrustedCertificateEntry("ca", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is copied without the password as JCEKS reads JKS key protection.
+	dest := New(WithStoreType(JCEKSStoreType))
+
+	report, err := dest.Import(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []ImportResult{
+		{Alias: "alias", DestAlias: "alias", Action: ImportAdded},
+		{Alias: "ca", DestAlias: "ca", Action: ImportAdded},
+	}; !reflect.DeepEqual(report, want) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if _, err := dest.GetPrivateKeyEntry("alias", []byte("keypassword")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dest.Import(src); !errors.Is(err, ErrAliasExists) {
+		t.Errorf("unexpected error for existing alias: %v", err)
+	}
+
+	report, err = dest.Import(src, WithConflictPolicy(ConflictRename), WithImportAliases("alias"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report) != 1 || report[0].DestAlias != "alias_1" || report[0].Action != ImportRenamed {
+		t.Errorf("unexpected rename report %+v", report)
+	}
+
+	report, err = dest.Import(src, WithConflictPolicy(ConflictRename), WithRenameSuffix("_Copy"),
+		WithImportAliases("alias"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report) != 1 || report[0].DestAlias != "alias_copy1" {
+		t.Errorf("unexpected rename report %+v", report)
+	}
+
+	if _, err := dest.GetPrivateKeyEntry("alias_Copy1", []byte("keypassword")); err != nil {
+		t.Errorf("renamed entry is not found: %v", err)
+	}
+
+	report, err = dest.Import(src, WithConflictPolicy(ConflictSkip))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report[0].Action != ImportSkipped || report[1].Action != ImportSkipped {
+		t.Errorf("unexpected skip report %+v", report)
+	}
+
+	if _, err := dest.Import(src, WithConflictPolicy(ConflictOverwrite)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dest.Aliases()) != 4 {
+		t.Errorf("unexpected aliases %v", dest.Aliases())
+	}
+
+	// PKCS12 can't read JKS key protection, the key is encrypted again and nothing is imported without the password.
+	p12 := New(WithStoreType(PKCS12StoreType))
+
+	if _, err := p12.Import(src); !errors.Is(err, ErrMissingPassword) || len(p12.Aliases()) != 0 {
+		t.Errorf("unexpected error for missing password: %v, aliases %v", err, p12.Aliases())
+	}
+
+	report, err = p12.Import(src, WithImportPasswords(map[string][]byte{"ALIAS": []byte("keypassword")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report[0].Reencrypted || report[1].Reencrypted {
+		t.Errorf("unexpected re-encryption report %+v", report)
+	}
+
+	if _, err := p12.GetPrivateKeyEntry("alias", []byte("keypassword")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportCopiesEntries(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := New()
+	if err := src.Load(bytes.NewReader(data), []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.SetTrustedCertificateEntry("ca", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := New()
+
+	// the same alias requested twice is imported once instead of conflicting with itself
+	report, err := dest.Import(src, WithImportAliases("alias", "ALIAS", "ca", "ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []ImportResult{
+		{Alias: "alias", DestAlias: "alias", Action: ImportAdded},
+		{Alias: "ca", DestAlias: "ca", Action: ImportAdded},
+	}; !reflect.DeepEqual(report, want) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	srcPKE := src.m["alias"].(PrivateKeyEntry)
+	srcTCE := src.m["ca"].(TrustedCertificateEntry)
+
+	shared := [][]byte{srcPKE.encryptedPrivateKey, srcPKE.CertificateChain[0].Content, srcTCE.Certificate.Content}
+	for _, b := range shared {
+		zeroing(b)
+	}
+
+	srcPKE.CertificateChain[0].Type = "changed"
+
+	if _, err := dest.GetPrivateKeyEntry("alias", []byte("keypassword")); err != nil {
+		t.Errorf("imported private key shares memory with the source: %v", err)
+	}
+
+	pke := dest.m["alias"].(PrivateKeyEntry)
+	if pke.CertificateChain[0].Type != "X.509" ||
+		bytes.Equal(pke.CertificateChain[0].Content, srcPKE.CertificateChain[0].Content) {
+		t.Error("imported certificate chain shares memory with the source")
+	}
+
+	tce, err := dest.GetTrustedCertificateEntry("ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tce.Certificate.X509(); err != nil {
+		t.Errorf("imported certificate shares memory with the source: %v", err)
+	}
+
+	// security keys read by Load keep the sealed object to store it unchanged
+	jceks := New(WithStoreType(JCEKSStoreType))
+	if err := jceks.SetSecurityKeyEntry("ske", SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := jceks.Store(&buf, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	src = New()
+	if err := src.Load(bytes.NewReader(buf.Bytes()), []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	dest = New(WithStoreType(JCEKSStoreType))
+	if _, err := dest.Import(src); err != nil {
+		t.Fatal(err)
+	}
+
+	srcSKE := src.m["ske"].(SecurityKeyEntry)
+	ske := dest.m["ske"].(SecurityKeyEntry)
+
+	if ske.sealed == nil || ske.sealed == srcSKE.sealed {
+		t.Fatal("imported sealed security key is shared with the source")
+	}
+
+	for _, b := range [][]byte{
+		srcSKE.EncryptedSecurityKey.EncryptedContent, srcSKE.sealed.serialized, srcSKE.sealed.encrypted.EncryptedContent,
+	} {
+		zeroing(b)
+	}
+
+	if _, err := dest.GetSecurityKeyEntry("ske", []byte("password")); err != nil {
+		t.Errorf("imported security key shares memory with the source: %v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), ske.sealed.serialized) ||
+		!reflect.DeepEqual(ske.sealed.encrypted, ske.EncryptedSecurityKey) {
+		t.Error("imported sealed security key shares memory with the source")
+	}
+}
+
+func TestImportRawTrailer(t *testing.T) {
+	t.Parallel()
+
+	tce := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	// the trailer hides entry "z" following the unknown entry "x"
+	trailer := &RawTrailer{
+		Tag:      9,
+		Alias:    "x",
+		Payload:  append([]byte("opaque"), 0, 0, 0, 2, 0, 1, 'z'),
+		EntryNum: 2,
+		version:  version02,
+	}
+
+	src := New()
+	src.trailer = trailer
+
+	if err := src.SetTrustedCertificateEntry("a", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := New()
+	if _, err := dest.Import(src); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := dest.RawTrailer(); ok || !reflect.DeepEqual(dest.Aliases(), []string{"a"}) {
+		t.Errorf("raw trailer is imported, aliases %v", dest.Aliases())
+	}
+
+	plain := New(WithOrderedAliases())
+
+	for _, alias := range []string{"x", "z"} {
+		if err := plain.SetTrustedCertificateEntry(alias, tce); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, policy := range []ConflictPolicy{ConflictFail, ConflictOverwrite} {
+		if _, err := src.Import(plain, WithConflictPolicy(policy)); !errors.Is(err, ErrAliasExists) {
+			t.Errorf("%d: unexpected error importing aliases of raw trailer: %v", policy, err)
+		}
+	}
+
+	report, err := src.Import(plain, WithConflictPolicy(ConflictSkip))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report[0].Action != ImportSkipped || report[1].Action != ImportSkipped {
+		t.Errorf("unexpected skip report %+v", report)
+	}
+
+	report, err = src.Import(plain, WithConflictPolicy(ConflictRename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report[0].DestAlias != "x_1" || report[1].DestAlias != "z_1" {
+		t.Errorf("unexpected rename report %+v", report)
+	}
+}
@@ -9,10 +9,39 @@ import (
 	"github.com/jkeys089/jserial"
 )
 
-func newKeyRep(objDef map[string]interface{}) KeyRep {
+var (
+	ErrUnexpectedContent = errors.New("deserialize: unexpected content")
+	ErrMalformedObject   = errors.New("deserialize: malformed serialized object")
+)
+
+const (
+	keyRepClass        = "java.security.KeyRep"
+	secretKeySpecClass = "javax.crypto.spec.SecretKeySpec"
+	rawKeyFormat       = "RAW"
+)
+
+// newKeyRep reads the key from KeyRep or from SecretKeySpec, which Java seals for secret keys as is.
+func newKeyRep(objDef map[string]interface{}) (KeyRep, error) {
+	// classes of the object are the keys of its inheritance hierarchy
+	extends, _ := objDef["extends"].(map[string]interface{})
+
+	switch {
+	case extends[secretKeySpecClass] != nil:
+		return newSecretKeySpec(objDef)
+	case extends[keyRepClass] == nil:
+		return KeyRep{}, fmt.Errorf("got object which is neither %s nor %s: %w",
+			keyRepClass, secretKeySpecClass, ErrUnexpectedContent)
+	}
+
 	k := KeyRep{}
-	encoded := intToBytes(objDef["encoded"].([]interface{}))
-	k.Encoded = encoded
+
+	if encoded, ok := objDef["encoded"].([]interface{}); ok {
+		k.Encoded = intToBytes(encoded)
+	}
+
+	if len(k.Encoded) == 0 {
+		return KeyRep{}, fmt.Errorf("got %s without encoded key: %w", keyRepClass, ErrUnexpectedContent)
+	}
 
 	if alg, ok := objDef["algorithm"].(string); ok {
 		k.Algorithm = alg
@@ -22,7 +51,26 @@ func newKeyRep(objDef map[string]interface{}) KeyRep {
 		k.Format = format
 	}
 
-	return k
+	return k, nil
+}
+
+// newSecretKeySpec reads the key from SecretKeySpec, its format is always RAW.
+func newSecretKeySpec(objDef map[string]interface{}) (KeyRep, error) {
+	k := KeyRep{Format: rawKeyFormat}
+
+	if key, ok := objDef["key"].([]interface{}); ok {
+		k.Encoded = intToBytes(key)
+	}
+
+	if len(k.Encoded) == 0 {
+		return KeyRep{}, fmt.Errorf("got %s without key: %w", secretKeySpecClass, ErrUnexpectedContent)
+	}
+
+	if alg, ok := objDef["algorithm"].(string); ok {
+		k.Algorithm = alg
+	}
+
+	return k, nil
 }
 
 func newSecurityKey(parseData map[string]interface{}) EncryptedSecurityKey {
@@ -68,37 +116,41 @@ func NewDecoder(reader io.Reader) Decoder {
 
 func (s *decoder) Decode(object interface{}) (err error) {
 	if object == nil {
-		err = errors.New("deserialize: object is nil")
-
-		return
+		return errors.New("deserialize: object is nil")
 	}
 
-	var (
-		content []interface{}
-	)
+	// the parser is not hardened against malformed streams
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrMalformedObject, r)
+		}
+	}()
 
-	if content, err = s.parser.ParseSerializedObject(); content == nil || len(content) != 1 {
-		return
+	content, err := s.parser.ParseSerializedObject()
+	// the parser stops on the first byte after the object, which is not a serialization type
+	if err != nil && !strings.Contains(err.Error(), "unknown type") {
+		return fmt.Errorf("deserialize: %w", err)
 	}
 
-	parseData, ok := content[0].(map[string]interface{})
+	if len(content) != 1 {
+		return fmt.Errorf("got %d objects: %w", len(content), ErrUnexpectedContent)
+	}
 
+	parseData, ok := content[0].(map[string]interface{})
 	if !ok {
-		panic("deserialize: unknown type of content")
+		return fmt.Errorf("got object of type %T: %w", content[0], ErrUnexpectedContent)
 	}
 
 	switch v := object.(type) {
 	case *KeyRep:
-		*v = newKeyRep(parseData)
+		if *v, err = newKeyRep(parseData); err != nil {
+			return err
+		}
 	case *EncryptedSecurityKey:
 		*v = newSecurityKey(parseData)
 	default:
-		err = fmt.Errorf(fmt.Sprintf("unknown jserial object %v", v))
-	}
-
-	if err != nil && strings.Contains(err.Error(), "unknown type") {
-		err = nil
+		return fmt.Errorf("unknown jserial object %v", v)
 	}
 
-	return err
+	return nil
 }
@@ -11,6 +11,10 @@ import (
 
 var (
 	javaKeyRepHex = "aced0005737200146a6176612e73656375726974792e4b6579526570bdf94fb3889aa5430200044c0009616c676f726974686d7400124c6a6176612f6c616e672f537472696e673b5b0007656e636f6465647400025b424c0006666f726d617471007e00014c00047479706574001b4c6a6176612f73656375726974792f4b657952657024547970653b7870740010504245576974684d4435416e64444553757200025b42acf317f8060854e00200007870000000087665744c654f63317400035241577e7200196a6176612e73656375726974792e4b6579526570245479706500000000000000001200007872000e6a6176612e6c616e672e456e756d00000000000000001200007870740006534543524554" // nolint
+	// SecretKeySpec of AES key 000102030405060708090a0b0c0d0e0f as sealed by Java's JCEKS keystore.
+	javaSecretKeySpecHex = "aced00057372001f6a617661782e63727970746f2e737065632e5365637265744b6579537065635b470b66e230614d0200024c0009616c676f726974686d7400124c6a6176612f6c616e672f537472696e673b5b00036b65797400025b427870740003414553757200025b42acf317f8060854e0020000787000000010000102030405060708090a0b0c0d0e0f" // nolint
+	// the same SecretKeySpec with an empty key.
+	javaEmptySecretKeySpecHex = "aced00057372001f6a617661782e63727970746f2e737065632e5365637265744b6579537065635b470b66e230614d0200024c0009616c676f726974686d7400124c6a6176612f6c616e672f537472696e673b5b00036b65797400025b427870740003414553757200025b42acf317f8060854e0020000787000000000" // nolint
 )
 
 type Unknown struct{}
@@ -27,6 +31,11 @@ func TestSerializator_Deserialize(t *testing.T) {
 		return data
 	}
 
+	var sealed bytes.Buffer
+	if err := NewEncoder(&sealed).Encode(EncryptedSecurityKey{ParamsAlg: "PBEWithMD5AndTripleDES"}); err != nil {
+		t.Fatal(err)
+	}
+
 	type fields struct {
 		parser *jserial.SerializedObjectParser
 	}
@@ -67,6 +76,38 @@ func TestSerializator_Deserialize(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"deserializeSecretKeySpec",
+			fields{jserial.NewSerializedObjectParser(bytes.NewReader(decode(javaSecretKeySpecHex)))},
+			args{&KeyRep{}},
+			&KeyRep{
+				Algorithm: "AES",
+				Format:    "RAW",
+				Encoded:   []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			},
+			false,
+		},
+		{
+			"deserializeEmptySecretKeySpec",
+			fields{jserial.NewSerializedObjectParser(bytes.NewReader(decode(javaEmptySecretKeySpecHex)))},
+			args{&KeyRep{}},
+			&KeyRep{},
+			true,
+		},
+		{
+			"deserializeSealedObjectAsKeyRep",
+			fields{jserial.NewSerializedObjectParser(bytes.NewReader(sealed.Bytes()))},
+			args{&KeyRep{}},
+			&KeyRep{},
+			true,
+		},
+		{
+			"deserializeString",
+			fields{jserial.NewSerializedObjectParser(bytes.NewReader(decode("aced000574000361626378")))},
+			args{&KeyRep{}},
+			&KeyRep{},
+			true,
+		},
 		{
 			"deserializeNilType",
 			fields{jserial.NewSerializedObjectParser(bytes.NewReader(decode(javaKeyRepHex)))},
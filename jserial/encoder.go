@@ -0,0 +1,350 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	streamMagic   uint16 = 0xaced
+	streamVersion uint16 = 5
+
+	tcNull         byte = 0x70
+	tcReference    byte = 0x71
+	tcClassDesc    byte = 0x72
+	tcObject       byte = 0x73
+	tcString       byte = 0x74
+	tcArray        byte = 0x75
+	tcEndBlockData byte = 0x78
+	tcEnum         byte = 0x7e
+
+	scSerializable byte = 0x02
+	scEnum         byte = 0x10
+
+	fieldTypeObject byte = 'L'
+	fieldTypeArray  byte = '['
+
+	baseWireHandle uint32 = 0x7e0000
+)
+
+const (
+	keyRepClassName       = "java.security.KeyRep"
+	keyRepTypeClassName   = "java.security.KeyRep$Type"
+	enumClassName         = "java.lang.Enum"
+	sealedObjectClassName = "javax.crypto.SealedObject"
+	keyProtectorClassName = "com.sun.crypto.provider.SealedObjectForKeyProtector"
+	byteArrayClassName    = "[B"
+	stringSignature       = "Ljava/lang/String;"
+	keyRepTypeSignature   = "Ljava/security/KeyRep$Type;"
+	keyRepUID             = -4757683898830641853
+	sealedObjectUID       = 4482838265551344752
+	keyProtectorUID       = -3650226485480866989
+	byteArrayUID          = -5984413125824719648
+	defaultKeyRepType     = "SECRET"
+)
+
+type fieldDesc struct {
+	typeCode  byte
+	name      string
+	signature string
+}
+
+type classDesc struct {
+	name   string
+	uid    int64
+	flags  byte
+	fields []fieldDesc
+	super  *classDesc
+}
+
+var (
+	byteArrayDesc = &classDesc{name: byteArrayClassName, uid: byteArrayUID, flags: scSerializable}
+
+	keyRepTypeDesc = &classDesc{
+		name:  keyRepTypeClassName,
+		flags: scSerializable | scEnum,
+		super: &classDesc{name: enumClassName, flags: scSerializable | scEnum},
+	}
+
+	keyRepDesc = &classDesc{
+		name:  keyRepClassName,
+		uid:   keyRepUID,
+		flags: scSerializable,
+		fields: []fieldDesc{
+			{typeCode: fieldTypeObject, name: "algorithm", signature: stringSignature},
+			{typeCode: fieldTypeArray, name: "encoded", signature: byteArrayClassName},
+			{typeCode: fieldTypeObject, name: "format", signature: stringSignature},
+			{typeCode: fieldTypeObject, name: "type", signature: keyRepTypeSignature},
+		},
+	}
+
+	sealedObjectDesc = &classDesc{
+		name:  keyProtectorClassName,
+		uid:   keyProtectorUID,
+		flags: scSerializable,
+		super: &classDesc{
+			name:  sealedObjectClassName,
+			uid:   sealedObjectUID,
+			flags: scSerializable,
+			fields: []fieldDesc{
+				{typeCode: fieldTypeArray, name: "encodedParams", signature: byteArrayClassName},
+				{typeCode: fieldTypeArray, name: "encryptedContent", signature: byteArrayClassName},
+				{typeCode: fieldTypeObject, name: "paramsAlg", signature: stringSignature},
+				{typeCode: fieldTypeObject, name: "sealAlg", signature: stringSignature},
+			},
+		},
+	}
+)
+
+type encoder struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	handles map[interface{}]uint32
+	next    uint32
+}
+
+// NewEncoder returns an Encoder writing java serialization streams into writer.
+// Every call of Encode writes a separate stream, as a new java.io.ObjectOutputStream does.
+func NewEncoder(writer io.Writer) Encoder {
+	return &encoder{w: writer}
+}
+
+func (s *encoder) Encode(object interface{}) error {
+	if object == nil {
+		return errors.New("serialize: object is nil")
+	}
+
+	s.buf.Reset()
+	s.handles = make(map[interface{}]uint32)
+	s.next = baseWireHandle
+
+	s.writeUint16(streamMagic)
+	s.writeUint16(streamVersion)
+
+	var err error
+
+	switch v := object.(type) {
+	case KeyRep:
+		err = s.writeKeyRep(v)
+	case *KeyRep:
+		err = s.writeKeyRep(*v)
+	case EncryptedSecurityKey:
+		err = s.writeSecurityKey(v)
+	case *EncryptedSecurityKey:
+		err = s.writeSecurityKey(*v)
+	default:
+		err = fmt.Errorf("unknown jserial object %v", v)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return fmt.Errorf("serialize: %w", err)
+	}
+
+	return nil
+}
+
+func (s *encoder) writeKeyRep(k KeyRep) error {
+	keyRepType := k.Type
+	if keyRepType == "" {
+		keyRepType = defaultKeyRepType
+	}
+
+	s.buf.WriteByte(tcObject)
+
+	if err := s.writeClassDesc(keyRepDesc); err != nil {
+		return err
+	}
+
+	s.newHandle(nil)
+
+	if err := s.writeString(k.Algorithm); err != nil {
+		return err
+	}
+
+	if err := s.writeByteArray(k.Encoded); err != nil {
+		return err
+	}
+
+	if err := s.writeString(k.Format); err != nil {
+		return err
+	}
+
+	return s.writeEnum(keyRepTypeDesc, keyRepType)
+}
+
+func (s *encoder) writeSecurityKey(sk EncryptedSecurityKey) error {
+	s.buf.WriteByte(tcObject)
+
+	if err := s.writeClassDesc(sealedObjectDesc); err != nil {
+		return err
+	}
+
+	s.newHandle(nil)
+
+	if err := s.writeByteArray(sk.EncodedParams); err != nil {
+		return err
+	}
+
+	if err := s.writeByteArray(sk.EncryptedContent); err != nil {
+		return err
+	}
+
+	if err := s.writeString(sk.ParamsAlg); err != nil {
+		return err
+	}
+
+	return s.writeString(sk.SealAlg)
+}
+
+func (s *encoder) writeClassDesc(desc *classDesc) error {
+	if desc == nil {
+		s.buf.WriteByte(tcNull)
+
+		return nil
+	}
+
+	if handle, ok := s.handles[desc]; ok {
+		s.writeReference(handle)
+
+		return nil
+	}
+
+	s.buf.WriteByte(tcClassDesc)
+	s.newHandle(desc)
+
+	if err := s.writeUTF(desc.name); err != nil {
+		return err
+	}
+
+	s.writeUint64(uint64(desc.uid))
+	s.buf.WriteByte(desc.flags)
+	s.writeUint16(uint16(len(desc.fields)))
+
+	for _, f := range desc.fields {
+		s.buf.WriteByte(f.typeCode)
+
+		if err := s.writeUTF(f.name); err != nil {
+			return err
+		}
+
+		if err := s.writeTypeString(f.signature); err != nil {
+			return err
+		}
+	}
+
+	s.buf.WriteByte(tcEndBlockData)
+
+	return s.writeClassDesc(desc.super)
+}
+
+// writeTypeString writes field type signature.
+// Java interns signatures, so the equal ones are written as references.
+func (s *encoder) writeTypeString(signature string) error {
+	if handle, ok := s.handles[signature]; ok {
+		s.writeReference(handle)
+
+		return nil
+	}
+
+	s.buf.WriteByte(tcString)
+	s.newHandle(signature)
+
+	return s.writeUTF(signature)
+}
+
+func (s *encoder) writeString(value string) error {
+	s.buf.WriteByte(tcString)
+	s.newHandle(nil)
+
+	return s.writeUTF(value)
+}
+
+func (s *encoder) writeByteArray(value []byte) error {
+	if value == nil {
+		s.buf.WriteByte(tcNull)
+
+		return nil
+	}
+
+	if uint64(len(value)) > math.MaxInt32 {
+		return fmt.Errorf("serialize: got array %d bytes long, max length is %d", len(value), math.MaxInt32)
+	}
+
+	s.buf.WriteByte(tcArray)
+
+	if err := s.writeClassDesc(byteArrayDesc); err != nil {
+		return err
+	}
+
+	s.newHandle(nil)
+	s.writeUint32(uint32(len(value)))
+	s.buf.Write(value)
+
+	return nil
+}
+
+func (s *encoder) writeEnum(desc *classDesc, constant string) error {
+	s.buf.WriteByte(tcEnum)
+
+	if err := s.writeClassDesc(desc); err != nil {
+		return err
+	}
+
+	s.newHandle(nil)
+
+	return s.writeString(constant)
+}
+
+func (s *encoder) writeUTF(value string) error {
+	if len(value) > math.MaxUint16 {
+		return fmt.Errorf("serialize: got string %d bytes long, max length is %d", len(value), math.MaxUint16)
+	}
+
+	s.writeUint16(uint16(len(value)))
+	s.buf.WriteString(value)
+
+	return nil
+}
+
+func (s *encoder) writeReference(handle uint32) {
+	s.buf.WriteByte(tcReference)
+	s.writeUint32(handle)
+}
+
+// newHandle assigns next wire handle, only the shared objects (key is not nil) are kept for the lookup.
+func (s *encoder) newHandle(key interface{}) {
+	if key != nil {
+		s.handles[key] = s.next
+	}
+
+	s.next++
+}
+
+func (s *encoder) writeUint16(value uint16) {
+	var b [2]byte
+
+	binary.BigEndian.PutUint16(b[:], value)
+	s.buf.Write(b[:])
+}
+
+func (s *encoder) writeUint32(value uint32) {
+	var b [4]byte
+
+	binary.BigEndian.PutUint32(b[:], value)
+	s.buf.Write(b[:])
+}
+
+func (s *encoder) writeUint64(value uint64) {
+	var b [8]byte
+
+	binary.BigEndian.PutUint64(b[:], value)
+	s.buf.Write(b[:])
+}
@@ -0,0 +1,103 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	expected, err := hex.DecodeString(javaKeyRepHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		object  interface{}
+		want    []byte
+		wantErr bool
+	}{
+		{
+			"serializeKeyRep",
+			KeyRep{Type: "SECRET", Algorithm: "PBEWithMD5AndDES", Format: "RAW", Encoded: []byte("vetLeOc1")},
+			expected,
+			false,
+		},
+		{
+			"serializeKeyRepDefaultType",
+			&KeyRep{Algorithm: "PBEWithMD5AndDES", Format: "RAW", Encoded: []byte("vetLeOc1")},
+			expected,
+			false,
+		},
+		{
+			"serializeNil",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"serializeUnknown",
+			&Unknown{},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := NewEncoder(&buf).Encode(tt.object)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !tt.wantErr && !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("Encode() got = %x, want=%x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_EncodeSecurityKey(t *testing.T) {
+	t.Parallel()
+
+	want := EncryptedSecurityKey{
+		EncodedParams:    []byte{48, 14, 4, 8, 1, 2, 3, 4, 5, 6, 7, 8, 2, 2, 7, 208},
+		EncryptedContent: []byte{0x91, 0x10, 0x29, 0xf1, 0x2b, 0x07, 0x73, 0x9f},
+		ParamsAlg:        "PBEWithMD5AndTripleDES",
+		SealAlg:          "PBEWithMD5AndTripleDES",
+	}
+
+	var buf bytes.Buffer
+
+	// two streams in a row, as JCEKS keeps them
+	for i := 0; i < 2; i++ {
+		if err := NewEncoder(&buf).Encode(want); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+
+	for i := 0; i < 2; i++ {
+		var got EncryptedSecurityKey
+
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() got = %+v, want=%+v", got, want)
+		}
+	}
+}
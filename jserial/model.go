@@ -4,6 +4,10 @@ type Decoder interface {
 	Decode(structure interface{}) error
 }
 
+type Encoder interface {
+	Encode(structure interface{}) error
+}
+
 // EncryptedSecurityKey describes encryption detail of security key.
 type EncryptedSecurityKey struct {
 	EncodedParams    []byte
@@ -6,6 +6,7 @@ import ( //nolint:gci
 	"crypto/des" //nolint:gosec,gci
 	"crypto/md5" //nolint:gosec,gci
 	"encoding/asn1"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -26,6 +27,10 @@ func (pbe pbeParams) Encode() (enc []byte) {
 
 const saltLength = 8
 
+// maxIterations is the iteration count limit of PBE algorithms, the same as JDK key protectors use.
+// It prevents hostile keystores from consuming CPU for a long time.
+const maxIterations = 5000000
+
 func generatePBEParams(rand io.Reader, iterations int) pbeParams {
 	salt := make([]byte, saltLength)
 
@@ -48,21 +53,38 @@ func decodeParams(encodedParams []byte) (*pbeParams, error) {
 		return nil, fmt.Errorf("decode pbe parameters: %w", err)
 	}
 
+	if err := checkIterations(pbe.Iterations); err != nil {
+		return nil, err
+	}
+
 	return pbe, nil
 }
 
+func checkIterations(iterations int) error {
+	if iterations < 1 {
+		return fmt.Errorf("got invalid iteration count %d", iterations)
+	}
+
+	return checkLimit("iteration count", uint64(iterations), maxIterations)
+}
+
 type Cipher struct {
 	block cipher.Block
 	iv    []byte
 }
 
 // NewDecryptCipher construct decrypter cipher by PBE params and password.
+// The salt must be 8 bytes long as the key is derived from its halves, otherwise ErrMalformedKey is returned.
 func NewDecryptCipher(password []byte, encodedParams []byte) (*Cipher, error) {
 	pbeParams, err := decodeParams(encodedParams)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(pbeParams.Salt) != saltLength {
+		return nil, fmt.Errorf("got salt %d bytes long, must be %d: %w", len(pbeParams.Salt), saltLength, ErrMalformedKey)
+	}
+
 	decipher := new(Cipher)
 	decipher.init(password, *pbeParams)
 
@@ -86,12 +108,29 @@ func (c *Cipher) Encrypt(src []byte) []byte {
 	return dst
 }
 
+// Decrypt returns decrypted src or nil if src is not a whole number of blocks or has invalid padding.
+// Use DecryptWithError to learn why src can not be decrypted.
 func (c *Cipher) Decrypt(src []byte) []byte {
+	dst, err := c.DecryptWithError(src)
+	if err != nil {
+		return nil
+	}
+
+	return dst
+}
+
+// DecryptWithError returns decrypted src. Invalid padding, the usual result of decryption with a wrong password,
+// is reported with ErrWrongPassword.
+func (c *Cipher) DecryptWithError(src []byte) ([]byte, error) {
+	if len(src) == 0 || len(src)%c.block.BlockSize() != 0 {
+		return nil, errors.New("got encrypted data of invalid length")
+	}
+
 	dec := cipher.NewCBCDecrypter(c.block, c.iv)
 	dst := make([]byte, len(src))
 	dec.CryptBlocks(dst, src)
 
-	return PKCS5Trimming(dst)
+	return PKCS5Unpadding(dst, c.block.BlockSize())
 }
 
 func (c *Cipher) init(password []byte, params pbeParams) {
@@ -122,6 +161,19 @@ func (c *Cipher) init(password []byte, params pbeParams) {
 func getDerivedKey(password []byte, salt []byte, count int) ([]byte, []byte) {
 	saltHalves := [][]byte{salt[:4], salt[4:]}
 
+	// the first half is inverted with the same loop as in Java's PBES1Core, including its salt[3-1] typo,
+	// so bytes a b c d become d a b d. The salt itself is kept unchanged.
+	if bytes.Equal(saltHalves[0], saltHalves[1]) {
+		inverted := append([]byte(nil), saltHalves[0]...)
+		for i := 0; i < 2; i++ {
+			tmp := inverted[i]
+			inverted[i] = inverted[3-i]
+			inverted[3-1] = tmp
+		}
+
+		saltHalves[0] = inverted
+	}
+
 	var derived [2][]byte
 	for i := 0; i < 2; i++ {
 		derived[i] = saltHalves[i]
@@ -147,8 +199,34 @@ func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
 	return append(ciphertext, pad...)
 }
 
+// PKCS5Trimming removes padding from encrypt, it returns nil if the padding is invalid.
+// Use PKCS5Unpadding to learn why the padding is invalid.
 func PKCS5Trimming(encrypt []byte) []byte {
-	padding := encrypt[len(encrypt)-1]
+	trimmed, err := PKCS5Unpadding(encrypt, des.BlockSize)
+	if err != nil {
+		return nil
+	}
+
+	return trimmed
+}
+
+// PKCS5Unpadding removes PKCS#5 padding checking all its bytes.
+// Invalid padding is the usual result of decryption with a wrong password, it is reported with ErrWrongPassword.
+func PKCS5Unpadding(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("got padded data of invalid length")
+	}
+
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize {
+		return nil, fmt.Errorf("got invalid padding: %w", ErrWrongPassword)
+	}
+
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("got invalid padding: %w", ErrWrongPassword)
+		}
+	}
 
-	return encrypt[:len(encrypt)-int(padding)]
+	return data[:len(data)-padding], nil
 }
@@ -1,6 +1,7 @@
 package keystore
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -172,6 +173,18 @@ func Test_getDerivedKey(t *testing.T) {
 				0x2f, 0xa3, 0xee, 0xcd, 0x18, 0xbf, 0xa7, 0xcb},
 			[]byte{0x36, 0x91, 0x08, 0x2b, 0xf4, 0x99, 0x2e, 0x92},
 		},
+		{
+			"equalSaltHalves",
+			args{
+				password: []byte{'m', 'y', 'p', 'a', 's', 's', 'w', 'o', 'r', 'd'},
+				salt:     []byte{0x01, 0x02, 0x03, 0x04, 0x01, 0x02, 0x03, 0x04},
+				count:    2000,
+			},
+			[]byte{0x02, 0xa8, 0xb6, 0x2e, 0x12, 0x36, 0x8e, 0xe1,
+				0xe8, 0x3d, 0xcc, 0x75, 0xf5, 0xd6, 0xcd, 0x36,
+				0x0d, 0x2c, 0xba, 0xa3, 0xa1, 0x79, 0xb7, 0xb4},
+			[]byte{0x34, 0x91, 0xa6, 0x78, 0x64, 0x64, 0xed, 0xe6},
+		},
 	}
 
 	for _, tt := range tests {
@@ -234,3 +247,93 @@ func TestCipher_Decrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestPKCS5Trimming(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{"valid", []byte{1, 2, 3, 4, 5, 3, 3, 3}, []byte{1, 2, 3, 4, 5}},
+		{"full block", []byte{8, 8, 8, 8, 8, 8, 8, 8}, []byte{}},
+		{"zero padding", []byte{1, 2, 3, 4, 5, 6, 7, 0}, nil},
+		{"padding larger than buffer", []byte{1, 2, 3, 4, 5, 6, 7, 255}, nil},
+		{"inconsistent padding", []byte{1, 2, 3, 4, 5, 6, 2, 3}, nil},
+		{"partial block", []byte{1, 1}, nil},
+		{"empty", nil, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := PKCS5Trimming(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PKCS5Trimming() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPKCS5Unpadding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		src           []byte
+		want          []byte
+		wrongPassword bool
+	}{
+		{"valid", []byte{1, 2, 3, 4, 5, 3, 3, 3}, []byte{1, 2, 3, 4, 5}, false},
+		{"zero padding", []byte{1, 2, 3, 4, 5, 6, 7, 0}, nil, true},
+		{"inconsistent padding", []byte{1, 2, 3, 4, 5, 6, 2, 3}, nil, true},
+		{"partial block", []byte{1, 1}, nil, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := PKCS5Unpadding(tt.src, 8)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PKCS5Unpadding() = %v, want %v", got, tt.want)
+			}
+
+			if (tt.want == nil) != (err != nil) || errors.Is(err, ErrWrongPassword) != tt.wrongPassword {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCipher_DecryptWithError(t *testing.T) {
+	t.Parallel()
+
+	params := pbeParams{Salt: []byte{1, 2, 3, 4, 5, 6, 7, 8}, Iterations: 2000}
+	encrypted := NewEncryptCipher([]byte("my_password"), params).Encrypt([]byte("my_secret"))
+
+	c, err := NewDecryptCipher([]byte("my_password"), params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := c.DecryptWithError(encrypted); err != nil || string(got) != "my_secret" {
+		t.Errorf("DecryptWithError() = %q, %v", got, err)
+	}
+
+	if _, err := c.DecryptWithError(encrypted[:5]); err == nil || errors.Is(err, ErrWrongPassword) {
+		t.Errorf("unexpected error for partial block: %v", err)
+	}
+
+	wrong, err := NewDecryptCipher([]byte("wrong_password"), params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := wrong.Decrypt(encrypted); got != nil {
+		t.Errorf("Decrypt() with wrong password = %v, want nil", got)
+	}
+}
@@ -14,6 +14,15 @@ import (
 
 const saltLen = 20
 
+const (
+	// jceIterations is a number of PBE iterations used for JCEKS keys.
+	jceIterations = 5000
+	// sealAlgorithm is a name of Java's PBE algorithm that seals JCEKS security keys.
+	sealAlgorithm            = "PBEWithMD5AndTripleDES"
+	secretKeyRepType         = "SECRET"
+	defaultSecurityKeyFormat = "RAW"
+)
+
 var (
 	jdkPrivateKeyAlgorithmOid = asn1.ObjectIdentifier([]int{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1})
 	jcePrivateKeyAlgorithmOid = asn1.ObjectIdentifier([]int{1, 3, 6, 1, 4, 1, 42, 2, 19, 1})
@@ -24,18 +33,49 @@ type keyInfo struct {
 	PrivateKey []byte
 }
 
+// privateKeyInfo is PKCS#8 PrivateKeyInfo, the format of private keys stored in keystores.
+type privateKeyInfo struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+	PublicKey  asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// javaStreamHeader starts every Java serialization stream.
+var javaStreamHeader = []byte{0xac, 0xed, 0x00, 0x05}
+
+// decrypt returns the private key decrypted with the password.
+// It returns ErrWrongPassword if the integrity check of JKS key fails. Other key protections have no integrity check,
+// so a key which is not PKCS#8 after decryption is reported with ErrWrongPassword too,
+// their corrupted encrypted data can't be told apart from a wrong password.
+// ErrMalformedKey is returned for malformed encrypted keys and JKS keys which are not PKCS#8
+// though they pass the integrity check.
 func decrypt(data []byte, password []byte) ([]byte, error) {
 	var keyInfo keyInfo
+	if err := unmarshalStrict(data, &keyInfo); err != nil {
+		return nil, fmt.Errorf("unmarshal encrypted key: %v: %w", err, ErrMalformedKey)
+	}
 
-	asn1Rest, err := asn1.Unmarshal(data, &keyInfo)
+	plainKey, err := decryptKey(keyInfo, password)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal encrypted key: %w", err)
+		return nil, err
 	}
 
-	if len(asn1Rest) > 0 {
-		return nil, errors.New("got extra data in encrypted key")
+	if err := unmarshalStrict(plainKey, &privateKeyInfo{}); err != nil {
+		zeroing(plainKey)
+
+		if keyInfo.Algo.Algorithm.Equal(jdkPrivateKeyAlgorithmOid) {
+			return nil, fmt.Errorf("got verified key which is not pkcs#8: %w", ErrMalformedKey)
+		}
+
+		return nil, fmt.Errorf("got key which is not pkcs#8: %w", ErrWrongPassword)
 	}
 
+	return plainKey, nil
+}
+
+func decryptKey(keyInfo keyInfo, password []byte) ([]byte, error) {
 	switch {
 	case keyInfo.Algo.Algorithm.Equal(jdkPrivateKeyAlgorithmOid):
 		return decryptJDKKey(keyInfo, password)
@@ -45,18 +85,42 @@ func decrypt(data []byte, password []byte) ([]byte, error) {
 			return nil, fmt.Errorf("decrypt security key: %w", err)
 		}
 
-		return dec.Decrypt(keyInfo.PrivateKey), nil
+		return dec.DecryptWithError(keyInfo.PrivateKey)
+	case keyInfo.Algo.Algorithm.Equal(pbes2Oid), keyInfo.Algo.Algorithm.Equal(pbeWithSHAAnd3KeyDESCBCOid):
+		return decryptPBE(keyInfo.Algo, keyInfo.PrivateKey, password)
 	default:
-		return nil, errors.New("got unsupported private key encryption algorithm")
+		return nil, ErrUnsupportedAlgorithm{OID: keyInfo.Algo.Algorithm}
 	}
 }
 
+// decryptJDKKey decrypts JKS key with the password, keys encrypted by older versions
+// with the legacy encoding of non-ASCII password are decrypted too.
 func decryptJDKKey(keyInfo keyInfo, password []byte) ([]byte, error) {
-	md := sha1.New()
-
 	passwordBytes := passwordBytes(password)
 	defer zeroing(passwordBytes)
 
+	plainKey, err := decryptJDKKeyWith(keyInfo, passwordBytes)
+	if !errors.Is(err, ErrWrongPassword) {
+		return plainKey, err
+	}
+
+	legacyBytes := legacyPasswordBytes(password)
+	if legacyBytes == nil {
+		return nil, err
+	}
+
+	defer zeroing(legacyBytes)
+
+	return decryptJDKKeyWith(keyInfo, legacyBytes)
+}
+
+func decryptJDKKeyWith(keyInfo keyInfo, passwordBytes []byte) ([]byte, error) {
+	md := sha1.New()
+
+	if len(keyInfo.PrivateKey) < saltLen+md.Size() {
+		return nil, fmt.Errorf("got too short encrypted key: %w", ErrMalformedKey)
+	}
+
 	salt := make([]byte, saltLen)
 	copy(salt, keyInfo.PrivateKey)
 	encryptedKeyLen := len(keyInfo.PrivateKey) - saltLen - md.Size()
@@ -106,14 +170,18 @@ func decryptJDKKey(keyInfo keyInfo, password []byte) ([]byte, error) {
 
 	digestOffset := saltLen + encryptedKeyLen
 	if !bytes.Equal(digest, keyInfo.PrivateKey[digestOffset:digestOffset+len(digest)]) {
-		return nil, errors.New("got invalid digest")
+		return nil, fmt.Errorf("got invalid key digest: %w", ErrWrongPassword)
 	}
 
 	return plainKey, nil
 }
 
 func encryptJCEKSKey(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
-	parameters := generatePBEParams(rand, 5000)
+	if err := checkJCEPassword(password); err != nil {
+		return nil, err
+	}
+
+	parameters := generatePBEParams(rand, jceIterations)
 
 	enc := NewEncryptCipher(password, parameters)
 
@@ -137,6 +205,21 @@ func encryptJCEKSKey(rand io.Reader, plainKey []byte, password []byte) ([]byte,
 	return encodedKey, nil
 }
 
+// encryptPKCS12Key encrypts key into PKCS#8 EncryptedPrivateKeyInfo using PBES2 scheme.
+func encryptPKCS12Key(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
+	algo, encryptedKey, err := encryptPBES2(rand, plainKey, password)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+
+	encodedKey, err := asn1.Marshal(keyInfo{Algo: algo, PrivateKey: encryptedKey})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encrypted key: %w", err)
+	}
+
+	return encodedKey, nil
+}
+
 func encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
 	md := sha1.New()
 
@@ -221,5 +304,56 @@ func decryptSecurityKey(encrypted jserial.EncryptedSecurityKey, password []byte)
 		return nil, fmt.Errorf("decrypt security key: %w", err)
 	}
 
-	return dec.Decrypt(encrypted.EncryptedContent), nil
+	return dec.DecryptWithError(encrypted.EncryptedContent)
+}
+
+// decodeKeyRep deserializes the security key unsealed with the password, Java seals either KeyRep or SecretKeySpec.
+// Sealed objects have no integrity check, so data which is not a Java serialization stream is reported
+// with ErrWrongPassword and a malformed serialized object or an object without a key with ErrMalformedKey.
+func decodeKeyRep(serializedKey []byte) (jserial.KeyRep, error) {
+	var keyRep jserial.KeyRep
+
+	if err := jserial.NewDecoder(bytes.NewReader(serializedKey)).Decode(&keyRep); err != nil {
+		if !bytes.HasPrefix(serializedKey, javaStreamHeader) {
+			return jserial.KeyRep{}, fmt.Errorf("got security key which is not serialized: %w", ErrWrongPassword)
+		}
+
+		return jserial.KeyRep{}, fmt.Errorf("deserialize security key: %v: %w", err, ErrMalformedKey)
+	}
+
+	return keyRep, nil
+}
+
+// encryptSecurityKey seals serialized key with Java's PBEWithMD5AndTripleDES algorithm
+// the same way com.sun.crypto.provider.KeyProtector does.
+func encryptSecurityKey(
+	rand io.Reader, serializedKey []byte, password []byte,
+) (jserial.EncryptedSecurityKey, error) {
+	if err := checkJCEPassword(password); err != nil {
+		return jserial.EncryptedSecurityKey{}, err
+	}
+
+	parameters := generatePBEParams(rand, jceIterations)
+
+	enc := NewEncryptCipher(password, parameters)
+
+	return jserial.EncryptedSecurityKey{
+		EncodedParams:    parameters.Encode(),
+		EncryptedContent: enc.Encrypt(serializedKey),
+		ParamsAlg:        sealAlgorithm,
+		SealAlg:          sealAlgorithm,
+	}, nil
+}
+
+// checkJCEPassword returns ErrNonASCIIPassword for passwords which Java's PBEKey rejects.
+// JCE key protector derives keys from password bytes as is, so keys protected with such passwords
+// could be read only by this library. Existing keys are still decrypted with them.
+func checkJCEPassword(password []byte) error {
+	for _, b := range password {
+		if b < 0x20 || b > 0x7e {
+			return fmt.Errorf("protect key with jce key protector: %w", ErrNonASCIIPassword)
+		}
+	}
+
+	return nil
 }
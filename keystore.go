@@ -1,15 +1,16 @@
 package keystore
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
-	"crypto/sha1"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
-	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
 )
@@ -20,22 +21,93 @@ var (
 	ErrEmptyPrivateKey         = errors.New("empty private key")
 	ErrEmptyCertificateType    = errors.New("empty certificate type")
 	ErrEmptyCertificateContent = errors.New("empty certificate content")
+	ErrEmptySecurityKey        = errors.New("empty security key")
+	ErrEmptyAlgorithm          = errors.New("empty algorithm")
 	ErrShortPassword           = errors.New("short password")
+	ErrNonASCIIPassword        = errors.New("password is not ascii")
+	ErrUnsupportedEntryType    = errors.New("entry type is not supported by the keystore type")
+	ErrUnsupportedFormat       = errors.New("unsupported keystore format")
+	ErrLimitExceeded           = errors.New("keystore exceeds limit")
+	ErrInvalidDigest           = errors.New("invalid digest")
+	ErrUnknownVersion          = errors.New("unknown version")
+	ErrWrongPassword           = errors.New("wrong password")
+	ErrMalformedKey            = errors.New("malformed key")
+	ErrAliasCollision          = errors.New("alias collision")
+	ErrMalformedString         = errors.New("malformed modified utf-8 string")
+	ErrUnsupportedKeyFormat    = errors.New("unsupported security key format")
 )
 
+// ErrUnknownFormat is returned by Load if the keystore header does not match any known format.
+type ErrUnknownFormat struct {
+	Magic []byte
+}
+
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("unknown keystore format with magic %x", e.Magic)
+}
+
+// ErrUnsupportedAlgorithm is returned if the keystore uses an algorithm identified by OID which is not supported.
+type ErrUnsupportedAlgorithm struct {
+	OID asn1.ObjectIdentifier
+}
+
+func (e ErrUnsupportedAlgorithm) Error() string {
+	return fmt.Sprintf("unsupported algorithm %s", e.OID)
+}
+
+// ErrCorrupt is returned by Load and Decoder if the keystore data is malformed or truncated.
+// Offset is the position in the keystore where the failed entry or the header starts or -1 for PKCS12 bags,
+// which may be encrypted. Entry is the index of the failed entry or PKCS12 bag or -1 for the keystore header,
+// Alias is the alias of the failed entry if it has been read. Err is the cause of the failure,
+// e.g. io.ErrUnexpectedEOF or ErrUnknownVersion. Exceeded load limits are not reported as ErrCorrupt.
+type ErrCorrupt struct {
+	Offset int64
+	Entry  int
+	Alias  string
+	Err    error
+}
+
+func (e ErrCorrupt) Error() string {
+	if e.Entry < 0 {
+		return fmt.Sprintf("corrupt keystore header at offset %d: %v", e.Offset, e.Err)
+	}
+
+	if e.Offset < 0 {
+		return fmt.Sprintf("corrupt keystore entry %d %q: %v", e.Entry, e.Alias, e.Err)
+	}
+
+	return fmt.Sprintf("corrupt keystore entry %d %q at offset %d: %v", e.Entry, e.Alias, e.Offset, e.Err)
+}
+
+// Unwrap returns the cause of the failure, io.EOF is reported as io.ErrUnexpectedEOF
+// as the keystore can't end inside the header or an entry.
+func (e ErrCorrupt) Unwrap() error {
+	if errors.Is(e.Err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return e.Err
+}
+
 const minPasswordLen = 6
 const (
-	JDKStoreType   = 0
-	JCEKSStoreType = 1
+	JDKStoreType    = 0
+	JCEKSStoreType  = 1
+	PKCS12StoreType = 2
 )
 
-// KeyStore is a mapping of alias to pointer to PrivateKeyEntry or TrustedCertificateEntry.
+// KeyStore is a mapping of alias to PrivateKeyEntry, TrustedCertificateEntry or SecurityKeyEntry.
 type KeyStore struct {
-	m map[string]interface{}
-
-	ordered   bool
-	caseExact bool
-	storeType int
+	m       map[string]interface{}
+	order   *entryOrder
+	trailer *RawTrailer
+	// version is a version of JKS or JCEKS keystore read by Load, zero for the latest one.
+	version uint32
+
+	ordered          bool
+	insertionOrdered bool
+	caseExact        bool
+	storeType        int
 }
 
 // PrivateKeyEntry is an entry for private keys and associated certificates.
@@ -60,10 +132,52 @@ type Certificate struct {
 }
 
 // SecurityKeyEntry is entry for JCEKS security key.
+// Algorithm is a standard java name of the key algorithm (e.g. AES, HmacSHA256, DESede),
+// Format is the encoding of SecurityKey, only "RAW" is supported as Java can't read secret keys
+// serialized in other formats. "RAW" is used if empty.
 type SecurityKeyEntry struct {
 	CreationTime         time.Time
 	SecurityKey          []byte
+	Algorithm            string
+	Format               string
 	EncryptedSecurityKey jserial.EncryptedSecurityKey
+
+	// sealed keeps the serialized sealed object read by Load to write it back unchanged.
+	sealed *sealedSecurityKey
+}
+
+// sealedSecurityKey is a serialized sealed object of EncryptedSecurityKey.
+type sealedSecurityKey struct {
+	serialized []byte
+	encrypted  jserial.EncryptedSecurityKey
+}
+
+// RawTrailer is the tail of JKS or JCEKS keystore starting at an entry with a tag the library can't interpret.
+// Keystore entries have no length, so neither the entry nor the following ones can be delimited and read,
+// that's why it is a trailer rather than a single raw entry.
+// Tag, Alias and CreationTime are read as for other entries, Payload keeps the rest of the keystore as is
+// and EntryNum is the number of entries in the trailer including the first one.
+// Store writes the trailer back unchanged after the other entries.
+type RawTrailer struct {
+	Tag          uint32
+	Alias        string
+	CreationTime time.Time
+	Payload      []byte
+	EntryNum     uint32
+
+	// version is a version of the keystore the trailer is read from.
+	version uint32
+}
+
+// containsAlias reports whether the payload contains the alias encoded as in the keystore entries.
+func (t RawTrailer) containsAlias(alias string) bool {
+	encoded := encodeModifiedUTF8(alias)
+
+	var length [2]byte
+
+	byteOrder.PutUint16(length[:], uint16(len(encoded)))
+
+	return bytes.Contains(t.Payload, append(length[:], encoded...))
 }
 
 type Option func(store *KeyStore)
@@ -71,16 +185,111 @@ type Option func(store *KeyStore)
 // WithOrderedAliases sets ordered option to true. Orders aliases alphabetically.
 func WithOrderedAliases() Option { return func(ks *KeyStore) { ks.ordered = true } }
 
-// WithCaseExactAliases sets caseExact option to true. Preserves original case of aliases.
+// WithInsertionOrderedAliases sets insertionOrdered option to true. Orders aliases by insertion,
+// entries read by Load keep the order of the keystore.
+func WithInsertionOrderedAliases() Option { return func(ks *KeyStore) { ks.insertionOrdered = true } }
+
+// WithCaseExactAliases sets caseExact option to true. Preserves original case of aliases
+// like Java's CaseExactJKS keystore type. Otherwise aliases are lower-cased with Locale.ENGLISH rules.
 func WithCaseExactAliases() Option { return func(ks *KeyStore) { ks.caseExact = true } }
 
 // WithStoreType sets storeType option value. The default keystore type is "jks" (storeType value is 0),
-// which is a proprietary format. Other keystore formats are available: "jceks" (storeType value is 0).
+// which is a proprietary format. Other keystore formats are available: "jceks" (storeType value is 1)
+// and "pkcs12" (storeType value is 2).
 func WithStoreType(storeType int) Option { return func(ks *KeyStore) { ks.storeType = storeType } }
 
+// LoadOption configures Load and NewDecoder.
+type LoadOption func(options *loadOptions)
+
+type loadOptions struct {
+	skipIntegrityCheck bool
+	maxEntries         int
+	maxCertificateSize int
+	maxKeySize         int
+	maxTrailerSize     int
+	maxIterations      int
+	maxSize            int64
+	onAliasCollision   func(alias, previous string) error
+	onSkippedEntry     func(alias string, err error)
+}
+
+// WithoutIntegrityCheck skips verification of the keystore signature, so the keystore can be read
+// without the password. Integrity of such keystore is not checked, use Verify to check it separately.
+// Keys are still encrypted with their own passwords, PKCS12 certificates encrypted with the password
+// can't be read without it.
+func WithoutIntegrityCheck() LoadOption {
+	return func(options *loadOptions) { options.skipIntegrityCheck = true }
+}
+
+// WithMaxEntries limits number of keystore entries, the number from the keystore header is checked
+// before reading entries. Zero value means no limit.
+func WithMaxEntries(n int) LoadOption {
+	return func(options *loadOptions) { options.maxEntries = n }
+}
+
+// WithMaxCertificateSize limits size of every certificate in bytes,
+// the size is checked before reading the certificate. Zero value means no limit.
+func WithMaxCertificateSize(n int) LoadOption {
+	return func(options *loadOptions) { options.maxCertificateSize = n }
+}
+
+// WithMaxKeySize limits size of every encrypted private key and serialized sealed security key in bytes,
+// the size is checked before reading the private key, reading of the sealed key stops as soon as it exceeds the limit.
+// Zero value means no limit.
+func WithMaxKeySize(n int) LoadOption {
+	return func(options *loadOptions) { options.maxKeySize = n }
+}
+
+// WithMaxTrailerSize limits size of RawTrailer payload in bytes, reading stops as soon as it exceeds the limit.
+// Zero value means no limit, the payload is limited only by WithMaxSize then.
+func WithMaxTrailerSize(n int) LoadOption {
+	return func(options *loadOptions) { options.maxTrailerSize = n }
+}
+
+// defaultMaxIterations is the iteration count limit of Load unless WithMaxIterations is used,
+// it allows keys protected by Java with the default iteration counts.
+const defaultMaxIterations = 500000
+
+// WithMaxIterations limits iteration count of key derivation for every key, PKCS12 mac and encrypted contents,
+// the count is checked by Load before any key is derived from the password. The default limit is 500000.
+// Zero value means the limit of 5000000 iterations, which is always checked when a key is derived.
+func WithMaxIterations(n int) LoadOption {
+	return func(options *loadOptions) { options.maxIterations = n }
+}
+
+// WithMaxSize limits total size of the keystore in bytes, reading stops as soon as the limit is exceeded.
+// Zero value means no limit.
+func WithMaxSize(n int64) LoadOption {
+	return func(options *loadOptions) { options.maxSize = n }
+}
+
+// WithOnAliasCollision sets function called by Load for every alias of the keystore equal to a previous one
+// after the conversion instead of failing with ErrAliasCollision. The later entry replaces the previous one as in Java
+// unless the function returns an error, which stops Load.
+func WithOnAliasCollision(onAliasCollision func(alias, previous string) error) LoadOption {
+	return func(options *loadOptions) { options.onAliasCollision = onAliasCollision }
+}
+
+// WithOnSkippedEntry sets function called by Load for every PKCS12 bag which is skipped as Java does,
+// e.g. unencrypted key bags, secret bags or certificates which are neither trusted nor in a private key chain.
+// The alias is the friendly name of the bag or its hex encoded local key id.
+func WithOnSkippedEntry(onSkippedEntry func(alias string, err error)) LoadOption {
+	return func(options *loadOptions) { options.onSkippedEntry = onSkippedEntry }
+}
+
+func newLoadOptions(options []LoadOption) loadOptions {
+	opts := loadOptions{maxIterations: defaultMaxIterations}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return opts
+}
+
 // New returns new initialized instance of the KeyStore.
 func New(options ...Option) KeyStore {
-	ks := KeyStore{m: make(map[string]interface{})}
+	ks := KeyStore{m: make(map[string]interface{}), order: newEntryOrder()}
 
 	for _, option := range options {
 		option(&ks)
@@ -90,26 +299,33 @@ func New(options ...Option) KeyStore {
 }
 
 // Store signs keystore using password and writes its representation into w
+// Entries are written alphabetically if keystore created using WithOrderedAliases option,
+// otherwise entries read by Load keep their order followed by the added ones in insertion order.
+// Unchanged JKS and JCEKS keystores are written byte for byte as they were read with the same password.
+// Keystores are written in the version read by Load, version 1 keystores can hold only X.509 certificates.
 // It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) Store(w io.Writer, password []byte) error {
-	if len(password) < minPasswordLen {
+	if utf8.RuneCount(password) < minPasswordLen {
 		return fmt.Errorf("password must be at least %d characters: %w", minPasswordLen, ErrShortPassword)
 	}
 
-	kse := keyStoreEncoder{
-		w:  w,
-		md: sha1.New(),
+	if ks.storeType == PKCS12StoreType {
+		return ks.storePKCS12(w, password)
 	}
 
-	passwordBytes := passwordBytes(password)
-	defer zeroing(passwordBytes)
+	md, err := newKeyStoreDigest(password)
+	if err != nil {
+		return err
+	}
 
-	if _, err := kse.md.Write(passwordBytes); err != nil {
-		return fmt.Errorf("update digest with password: %w", err)
+	kse := keyStoreEncoder{
+		w:       w,
+		md:      md,
+		version: ks.version,
 	}
 
-	if _, err := kse.md.Write(whitenerMessage); err != nil {
-		return fmt.Errorf("update digest with whitener message: %w", err)
+	if kse.version == 0 {
+		kse.version = version02
 	}
 
 	var magic uint32
@@ -124,16 +340,16 @@ func (ks KeyStore) Store(w io.Writer, password []byte) error {
 	if err := kse.writeUint32(magic); err != nil {
 		return fmt.Errorf("write jksmagic: %w", err)
 	}
-	// always write latest version
-	if err := kse.writeUint32(version02); err != nil {
+
+	if err := kse.writeUint32(kse.version); err != nil {
 		return fmt.Errorf("write version: %w", err)
 	}
 
-	if err := kse.writeUint32(uint32(len(ks.m))); err != nil {
+	if err := kse.writeUint32(ks.entryNum()); err != nil {
 		return fmt.Errorf("write number of entries: %w", err)
 	}
 
-	for _, alias := range ks.Aliases() {
+	for _, alias := range ks.storeAliases() {
 		switch typedEntry := ks.m[alias].(type) {
 		case PrivateKeyEntry:
 			if err := kse.writePrivateKeyEntry(alias, typedEntry); err != nil {
@@ -143,11 +359,25 @@ func (ks KeyStore) Store(w io.Writer, password []byte) error {
 			if err := kse.writeTrustedCertificateEntry(alias, typedEntry); err != nil {
 				return fmt.Errorf("write trusted certificate entry: %w", err)
 			}
+		case SecurityKeyEntry:
+			if ks.storeType != JCEKSStoreType {
+				return fmt.Errorf("write security key entry: %w", ErrUnsupportedEntryType)
+			}
+
+			if err := kse.writeSecurityKeyEntry(alias, typedEntry); err != nil {
+				return fmt.Errorf("write security key entry: %w", err)
+			}
 		default:
 			return errors.New("got invalid entry")
 		}
 	}
 
+	if ks.trailer != nil {
+		if err := kse.writeRawTrailer(*ks.trailer); err != nil {
+			return fmt.Errorf("write raw trailer: %w", err)
+		}
+	}
+
 	if err := kse.writeBytes(kse.md.Sum(nil)); err != nil {
 		return fmt.Errorf("write digest: %w", err)
 	}
@@ -155,102 +385,166 @@ func (ks KeyStore) Store(w io.Writer, password []byte) error {
 	return nil
 }
 
-// Load reads keystore representation from r and checks its signature.
+// Load reads keystore representation from r and checks its signature unless WithoutIntegrityCheck is used.
+// Aliases are lower-cased as Java does unless WithCaseExactAliases is used, which matches CaseExactJKS keystore type.
+// If two aliases of the keystore are equal after the conversion ErrAliasCollision is returned,
+// use WithOnAliasCollision to let the later entry win as in Java. The keystore is not changed if Load fails.
 // It is strongly recommended to fill password slice with zero after usage.
-func (ks *KeyStore) Load(r io.Reader, password []byte) error {
-	md := sha1.New()
+func (ks *KeyStore) Load(r io.Reader, password []byte, options ...LoadOption) error {
+	opts := newLoadOptions(options)
+	br := bufio.NewReader(newLimitedReader(r, opts.maxSize))
 
-	passwordBytes := passwordBytes(password)
-	defer zeroing(passwordBytes)
+	magic, err := peekMagic(br)
+	if err != nil {
+		return err
+	}
 
-	if _, err := md.Write(passwordBytes); err != nil {
-		return fmt.Errorf("update digest with password: %w", err)
+	storeType, err := detectStoreType(magic)
+	if err != nil {
+		return err
 	}
 
-	if _, err := md.Write(whitenerMessage); err != nil {
-		return fmt.Errorf("update digest with whitener message: %w", err)
+	// entries are read into a separate keystore to keep this one unchanged on failure
+	loaded := KeyStore{
+		m:         make(map[string]interface{}),
+		order:     newEntryOrder(),
+		caseExact: ks.caseExact,
+		storeType: storeType,
 	}
 
-	signReader := NewReader(r, md)
-	ksd := newKeyStoreDecoder(signReader, md)
+	if storeType == PKCS12StoreType {
+		err = loaded.loadPKCS12(br, password, opts)
+	} else {
+		err = loaded.loadKeyStore(br, password, opts)
+	}
 
-	readMagic, err := ksd.readUint32()
 	if err != nil {
-		return fmt.Errorf("read keystore type jks or jceks magic: %w", err)
+		return err
 	}
 
-	switch readMagic {
-	case jceksmagic:
-		ks.storeType = JCEKSStoreType
-	case jksmagic:
-		ks.storeType = JDKStoreType
-	default:
-		return fmt.Errorf("invalid magic: %w", err)
-	}
+	ks.storeType = storeType
+	ks.trailer = loaded.trailer
+	ks.version = loaded.version
 
-	version, err := ksd.readUint32()
-	if err != nil {
-		return fmt.Errorf("read version: %w", err)
+	for _, alias := range loaded.storeAliases() {
+		ks.setEntry(alias, loaded.m[alias])
 	}
 
-	entryNum, err := ksd.readUint32()
+	return nil
+}
+
+// loadKeyStore reads entries of JKS or JCEKS keystore.
+func (ks *KeyStore) loadKeyStore(r io.Reader, password []byte, opts loadOptions) error {
+	d, err := newDecoder(r, ks.storeType, password, opts)
 	if err != nil {
-		return fmt.Errorf("read number of entries: %w", err)
+		return err
 	}
 
-	for i := uint32(0); i < entryNum; i++ {
-		alias, entry, err := ksd.readEntry(version)
+	ks.version = d.version
+	loaded := make(map[string]string, d.Len())
+
+	for {
+		alias, entry, err := d.Next()
+		// Decoder returns unwrapped io.EOF only after the last entry, a truncated entry is never taken for the end.
+		if err == io.EOF { // nolint: errorlint
+			return nil
+		}
+
 		if err != nil {
-			return fmt.Errorf("read %d entry: %w", i, err)
+			return err
 		}
 
-		ks.m[alias] = entry
-	}
+		if trailer, ok := entry.(RawTrailer); ok {
+			ks.trailer = &trailer
 
-	verified, err := signReader.VerifySign()
-	if err != nil {
-		return fmt.Errorf("read digest: %w", err)
+			continue
+		}
+
+		if err := ks.setLoadedEntry(loaded, alias, entry, opts); err != nil {
+			return err
+		}
 	}
+}
+
+// setLoadedEntry adds the entry read from the keystore by the alias converted the same way as in setters.
+// loaded maps converted aliases of the already read entries to the original ones to detect collisions.
+func (ks KeyStore) setLoadedEntry(loaded map[string]string, alias string, entry interface{}, opts loadOptions) error {
+	converted := ks.convertAlias(alias)
 
-	if !verified {
-		return errors.New("got invalid digest")
+	if previous, ok := loaded[converted]; ok {
+		if opts.onAliasCollision == nil {
+			return fmt.Errorf("got alias %q colliding with %q: %w", alias, previous, ErrAliasCollision)
+		}
+
+		if err := opts.onAliasCollision(alias, previous); err != nil {
+			return fmt.Errorf("got alias %q colliding with %q: %w", alias, previous, err)
+		}
 	}
 
+	loaded[converted] = alias
+	ks.setEntry(converted, entry)
+
 	return nil
 }
 
+// entryNum returns number of keystore entries including the ones in the raw trailer.
+func (ks KeyStore) entryNum() uint32 {
+	n := uint32(len(ks.m))
+
+	if ks.trailer != nil {
+		n += ks.trailer.EntryNum
+	}
+
+	return n
+}
+
+// StoreType returns type of the keystore, it is detected by Load or set by WithStoreType option.
+func (ks KeyStore) StoreType() int {
+	return ks.storeType
+}
+
+// detectStoreType recognises keystore format by its first bytes.
+func detectStoreType(magic []byte) (int, error) {
+	switch byteOrder.Uint32(magic) {
+	case jksmagic:
+		return JDKStoreType, nil
+	case jceksmagic:
+		return JCEKSStoreType, nil
+	case bksVersion1, bksVersion2:
+		return 0, fmt.Errorf("got bks keystore: %w", ErrUnsupportedFormat)
+	}
+
+	// PKCS#12 PFX is DER SEQUENCE with long or indefinite form of length
+	if magic[0] == pkcs12SequenceTag && magic[1]&0x80 != 0 && magic[1] <= 0x84 {
+		return PKCS12StoreType, nil
+	}
+
+	return 0, ErrUnknownFormat{Magic: append([]byte(nil), magic...)}
+}
+
 // SetPrivateKeyEntry adds PrivateKeyEntry into keystore by alias encrypted with password.
 // It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) SetPrivateKeyEntry(alias string, entry PrivateKeyEntry, password []byte) error {
+	if err := ks.checkAlias(alias); err != nil {
+		return err
+	}
+
 	if err := entry.validate(); err != nil {
 		return fmt.Errorf("validate private key entry: %w", err)
 	}
 
-	if len(password) < minPasswordLen {
+	if utf8.RuneCount(password) < minPasswordLen {
 		return fmt.Errorf("password must be at least %d characters: %w", minPasswordLen, ErrShortPassword)
 	}
 
-	var (
-		epk []byte
-		err error
-	)
-
-	switch ks.storeType {
-	case JDKStoreType:
-		epk, err = encrypt(rand.Reader, entry.PrivateKey, password)
-	case JCEKSStoreType:
-		epk, err = encryptJCEKSKey(rand.Reader, entry.PrivateKey, password)
-	default:
-		err = errors.New("unsupported type of keystore")
-	}
-
+	epk, err := encryptPrivateKey(ks.storeType, entry.PrivateKey, password)
 	if err != nil {
 		return fmt.Errorf("encrypt private key: %w", err)
 	}
 
 	entry.encryptedPrivateKey = epk
 
-	ks.m[ks.convertAlias(alias)] = entry
+	ks.setEntry(ks.convertAlias(alias), entry)
 
 	return nil
 }
@@ -268,15 +562,22 @@ func (ks KeyStore) GetPrivateKeyEntry(alias string, password []byte) (PrivateKey
 		return PrivateKeyEntry{}, ErrWrongEntryType
 	}
 
-	dpk, err := decrypt(pke.encryptedPrivateKey, password)
+	return pke.Decrypt(password)
+}
+
+// Decrypt returns PrivateKeyEntry with the private key decrypted with the password.
+// It is useful for entries returned by Decoder, entries of KeyStore are decrypted by GetPrivateKeyEntry.
+// It is strongly recommended to fill password slice with zero after usage.
+func (e PrivateKeyEntry) Decrypt(password []byte) (PrivateKeyEntry, error) {
+	dpk, err := decrypt(e.encryptedPrivateKey, password)
 	if err != nil {
 		return PrivateKeyEntry{}, fmt.Errorf("decrypte private key: %w", err)
 	}
 
-	pke.encryptedPrivateKey = nil
-	pke.PrivateKey = dpk
+	e.encryptedPrivateKey = nil
+	e.PrivateKey = dpk
 
-	return pke, nil
+	return e, nil
 }
 
 // IsPrivateKeyEntry returns true if the keystore has PrivateKeyEntry by the alias.
@@ -286,13 +587,33 @@ func (ks KeyStore) IsPrivateKeyEntry(alias string) bool {
 	return ok
 }
 
+// GetPrivateKeyEntryCertificateChain returns certificate chain of PrivateKeyEntry
+// from the keystore by the alias without decrypting the private key.
+func (ks KeyStore) GetPrivateKeyEntryCertificateChain(alias string) ([]Certificate, error) {
+	e, ok := ks.m[ks.convertAlias(alias)]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+
+	pke, ok := e.(PrivateKeyEntry)
+	if !ok {
+		return nil, ErrWrongEntryType
+	}
+
+	return pke.CertificateChain, nil
+}
+
 // SetTrustedCertificateEntry adds TrustedCertificateEntry into keystore by alias.
 func (ks KeyStore) SetTrustedCertificateEntry(alias string, entry TrustedCertificateEntry) error {
+	if err := ks.checkAlias(alias); err != nil {
+		return err
+	}
+
 	if err := entry.validate(); err != nil {
 		return fmt.Errorf("validate trusted certificate entry: %w", err)
 	}
 
-	ks.m[ks.convertAlias(alias)] = entry
+	ks.setEntry(ks.convertAlias(alias), entry)
 
 	return nil
 }
@@ -319,6 +640,56 @@ func (ks KeyStore) IsTrustedCertificateEntry(alias string) bool {
 	return ok
 }
 
+// SetSecurityKeyEntry adds SecurityKeyEntry into JCEKS keystore by alias sealed with password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) SetSecurityKeyEntry(alias string, entry SecurityKeyEntry, password []byte) error {
+	if err := ks.checkAlias(alias); err != nil {
+		return err
+	}
+
+	if err := entry.validate(); err != nil {
+		return fmt.Errorf("validate security key entry: %w", err)
+	}
+
+	if utf8.RuneCount(password) < minPasswordLen {
+		return fmt.Errorf("password must be at least %d characters: %w", minPasswordLen, ErrShortPassword)
+	}
+
+	if ks.storeType != JCEKSStoreType {
+		return fmt.Errorf("set security key entry: %w", ErrUnsupportedEntryType)
+	}
+
+	if entry.Format == "" {
+		entry.Format = defaultSecurityKeyFormat
+	}
+
+	var keyRep bytes.Buffer
+
+	err := jserial.NewEncoder(&keyRep).Encode(jserial.KeyRep{
+		Type:      secretKeyRepType,
+		Algorithm: entry.Algorithm,
+		Format:    entry.Format,
+		Encoded:   entry.SecurityKey,
+	})
+	if err != nil {
+		return fmt.Errorf("serialize security key: %w", err)
+	}
+
+	defer zeroing(keyRep.Bytes())
+
+	entry.SecurityKey = nil
+	entry.EncryptedSecurityKey, err = encryptSecurityKey(rand.Reader, keyRep.Bytes(), password)
+	if err != nil {
+		return err
+	}
+
+	ks.setEntry(ks.convertAlias(alias), entry)
+
+	return nil
+}
+
+// GetSecurityKeyEntry returns SecurityKeyEntry from the keystore by the alias decrypted with the password.
+// It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) GetSecurityKeyEntry(alias string, password []byte) (SecurityKeyEntry, error) {
 	e, ok := ks.m[ks.convertAlias(alias)]
 	if !ok {
@@ -330,39 +701,105 @@ func (ks KeyStore) GetSecurityKeyEntry(alias string, password []byte) (SecurityK
 		return SecurityKeyEntry{}, ErrWrongEntryType
 	}
 
-	dsk, err := decryptSecurityKey(ske.EncryptedSecurityKey, password)
+	return ske.Decrypt(password)
+}
+
+// Decrypt returns SecurityKeyEntry with the security key decrypted with the password.
+// It is useful for entries returned by Decoder, entries of KeyStore are decrypted by GetSecurityKeyEntry.
+// It is strongly recommended to fill password slice with zero after usage.
+func (e SecurityKeyEntry) Decrypt(password []byte) (SecurityKeyEntry, error) {
+	dsk, err := decryptSecurityKey(e.EncryptedSecurityKey, password)
 	if err != nil {
 		return SecurityKeyEntry{}, fmt.Errorf("decrypt security key: %w", err)
 	}
 
-	repKey := &jserial.KeyRep{}
-	err = jserial.NewDecoder(bytes.NewReader(dsk)).Decode(repKey)
-
+	repKey, err := decodeKeyRep(dsk)
 	if err != nil {
-		return SecurityKeyEntry{}, fmt.Errorf("decrypt security key: %w", err)
+		return SecurityKeyEntry{}, err
 	}
 
-	ske.SecurityKey = repKey.Encoded
-	ske.EncryptedSecurityKey = jserial.EncryptedSecurityKey{}
+	e.SecurityKey = repKey.Encoded
+	e.Algorithm = repKey.Algorithm
+	e.Format = repKey.Format
+	e.EncryptedSecurityKey = jserial.EncryptedSecurityKey{}
+	e.sealed = nil
 
-	return ske, nil
+	return e, nil
+}
+
+// IsSecurityKeyEntry returns true if the keystore has SecurityKeyEntry by the alias.
+func (ks KeyStore) IsSecurityKeyEntry(alias string) bool {
+	_, ok := ks.m[ks.convertAlias(alias)].(SecurityKeyEntry)
+
+	return ok
+}
+
+// RawTrailer returns the raw trailer read by Load from the keystore with an entry it can't interpret.
+// Aliases of the trailer entries are not returned by Aliases and can't be used by setters.
+func (ks KeyStore) RawTrailer() (RawTrailer, bool) {
+	if ks.trailer == nil {
+		return RawTrailer{}, false
+	}
+
+	return *ks.trailer, true
+}
+
+// checkAlias returns ErrAliasExists if an entry of the raw trailer may have the alias,
+// Java would read the entry from the trailer instead of the one set by the alias.
+func (ks KeyStore) checkAlias(alias string) error {
+	if ks.trailer == nil {
+		return nil
+	}
+
+	converted := ks.convertAlias(alias)
+
+	if converted == ks.convertAlias(ks.trailer.Alias) ||
+		ks.trailer.containsAlias(alias) || ks.trailer.containsAlias(converted) {
+		return fmt.Errorf("got alias %q which may be used in raw trailer: %w", alias, ErrAliasExists)
+	}
+
+	return nil
+}
+
+// CreationTime returns creation time of the entry by the alias without decrypting it.
+func (ks KeyStore) CreationTime(alias string) (time.Time, error) {
+	switch e := ks.m[ks.convertAlias(alias)].(type) {
+	case PrivateKeyEntry:
+		return e.CreationTime, nil
+	case TrustedCertificateEntry:
+		return e.CreationTime, nil
+	case SecurityKeyEntry:
+		return e.CreationTime, nil
+	default:
+		return time.Time{}, ErrEntryNotFound
+	}
 }
 
 // DeleteEntry deletes entry from the keystore.
 func (ks KeyStore) DeleteEntry(alias string) {
-	delete(ks.m, ks.convertAlias(alias))
+	alias = ks.convertAlias(alias)
+
+	delete(ks.m, alias)
+
+	if ks.order != nil {
+		ks.order.delete(alias)
+	}
 }
 
 // Aliases returns slice of all aliases from the keystore.
-// Aliases returns slice of all aliases sorted alphabetically if keystore created using WithOrderedAliases option.
+// Aliases returns slice of all aliases sorted alphabetically if keystore created using WithOrderedAliases option
+// or in insertion order if keystore created using WithInsertionOrderedAliases option.
 func (ks KeyStore) Aliases() []string {
 	as := make([]string, 0, len(ks.m))
 	for a := range ks.m {
 		as = append(as, a)
 	}
 
-	if ks.ordered {
+	switch {
+	case ks.ordered:
 		sort.Strings(as)
+	case ks.insertionOrdered && ks.order != nil:
+		ks.order.sort(as)
 	}
 
 	return as
@@ -373,7 +810,21 @@ func (ks KeyStore) convertAlias(alias string) string {
 		return alias
 	}
 
-	return strings.ToLower(alias)
+	return toLowerEnglish(alias)
+}
+
+// encryptPrivateKey encrypts key with the protection algorithm of the keystore type.
+func encryptPrivateKey(storeType int, plainKey []byte, password []byte) ([]byte, error) {
+	switch storeType {
+	case JDKStoreType:
+		return encrypt(rand.Reader, plainKey, password)
+	case JCEKSStoreType:
+		return encryptJCEKSKey(rand.Reader, plainKey, password)
+	case PKCS12StoreType:
+		return encryptPKCS12Key(rand.Reader, plainKey, password)
+	default:
+		return nil, errors.New("unsupported type of keystore")
+	}
 }
 
 func (e PrivateKeyEntry) validate() error {
@@ -394,6 +845,22 @@ func (e TrustedCertificateEntry) validate() error {
 	return e.Certificate.validate()
 }
 
+func (e SecurityKeyEntry) validate() error {
+	if len(e.SecurityKey) == 0 {
+		return ErrEmptySecurityKey
+	}
+
+	if len(e.Algorithm) == 0 {
+		return ErrEmptyAlgorithm
+	}
+
+	if e.Format != "" && e.Format != defaultSecurityKeyFormat {
+		return fmt.Errorf("got format %q: %w", e.Format, ErrUnsupportedKeyFormat)
+	}
+
+	return nil
+}
+
 func (c Certificate) validate() error {
 	if len(c.Type) == 0 {
 		return ErrEmptyCertificateType
@@ -1,14 +1,20 @@
 package keystore
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
 	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
 )
 
 func TestSetGetMethods(t *testing.T) {
@@ -295,6 +301,261 @@ func TestLoadKeyPassword(t *testing.T) {
 	}
 }
 
+func TestStoreLoadSecurityKeyEntry(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	securityKey := []byte("vetLeOc1")
+
+	var keyRep bytes.Buffer
+	if err := jserial.NewEncoder(&keyRep).Encode(jserial.KeyRep{
+		Algorithm: "PBEWithMD5AndDES",
+		Format:    "RAW",
+		Encoded:   securityKey,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	params := generatePBEParams(rand.Reader, 5000)
+	ske := SecurityKeyEntry{
+		CreationTime: time.Now(),
+		EncryptedSecurityKey: jserial.EncryptedSecurityKey{
+			EncodedParams:    params.Encode(),
+			EncryptedContent: NewEncryptCipher(password, params).Encrypt(keyRep.Bytes()),
+			ParamsAlg:        "PBEWithMD5AndTripleDES",
+			SealAlg:          "PBEWithMD5AndTripleDES",
+		},
+	}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	ks.m["ske-alias"] = ske
+
+	var first bytes.Buffer
+	if err := ks.Store(&first, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.Load(bytes.NewReader(first.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	actualSKE, err := loaded.GetSecurityKeyEntry("ske-alias", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(actualSKE.SecurityKey, securityKey) {
+		t.Errorf("unexpected security key '%v' '%v'", actualSKE.SecurityKey, securityKey)
+	}
+
+	var second bytes.Buffer
+	if err := loaded.Store(&second, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("stored keystores must be equal")
+	}
+
+	jks := New()
+	jks.m["ske-alias"] = ske
+
+	if err := jks.Store(&bytes.Buffer{}, password); !errors.Is(err, ErrUnsupportedEntryType) {
+		t.Errorf("unexpected error storing security key into jks: %v", err)
+	}
+}
+
+func TestSetGetSecurityKeyEntry(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	ske := SecurityKeyEntry{
+		CreationTime: time.Unix(1600000000, 0),
+		SecurityKey:  []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		Algorithm:    "AES",
+	}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	if err := ks.SetSecurityKeyEntry("ske-alias", ske, password); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.Load(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	actualSKE, err := loaded.GetSecurityKeyEntry("ske-alias", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ske.Format = "RAW"
+	actualSKE.EncryptedSecurityKey = jserial.EncryptedSecurityKey{}
+
+	if !reflect.DeepEqual(actualSKE, ske) {
+		t.Errorf("security key entries not equal '%+v' '%+v'", actualSKE, ske)
+	}
+
+	err = ks.SetSecurityKeyEntry("ske-alias", SecurityKeyEntry{Algorithm: "AES"}, password)
+	if !errors.Is(err, ErrEmptySecurityKey) {
+		t.Errorf("unexpected error for empty security key: %v", err)
+	}
+
+	pkcs8 := SecurityKeyEntry{SecurityKey: ske.SecurityKey, Algorithm: "AES", Format: "PKCS#8"}
+	if err := ks.SetSecurityKeyEntry("ske-alias", pkcs8, password); !errors.Is(err, ErrUnsupportedKeyFormat) {
+		t.Errorf("unexpected error for non-raw format: %v", err)
+	}
+
+	if err := New().SetSecurityKeyEntry("ske-alias", ske, password); !errors.Is(err, ErrUnsupportedEntryType) {
+		t.Errorf("unexpected error for jks keystore: %v", err)
+	}
+}
+
+func TestLoadKeytoolSecurityKeyEntry(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	data := readKeytoolKeyStore(t, "keytool_seckey.jceks")
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(data), password); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, ok := ks.m["aes"].(SecurityKeyEntry)
+	if !ok {
+		t.Fatal("security key entry not found")
+	}
+
+	// SealedObject serialized by Java is the test vector of the encoder.
+	var sealed bytes.Buffer
+	if err := jserial.NewEncoder(&sealed).Encode(encrypted.EncryptedSecurityKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(data, sealed.Bytes()) {
+		t.Errorf("serialized sealed object %x is not found in the keystore written by java", sealed.Bytes())
+	}
+
+	if !bytes.Equal(sealed.Bytes(), encrypted.sealed.serialized) {
+		t.Errorf("serialized sealed object %x doesn't match the one read by Load %x",
+			sealed.Bytes(), encrypted.sealed.serialized)
+	}
+
+	var stored bytes.Buffer
+	if err := ks.Store(&stored, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stored.Bytes(), data) {
+		t.Error("unchanged keystore written by java is not stored byte for byte")
+	}
+
+	ske, err := ks.GetSecurityKeyEntry("aes", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ske.Algorithm != "AES" || ske.Format != "RAW" || len(ske.SecurityKey) != 16 {
+		t.Errorf("unexpected security key entry %+v", ske)
+	}
+}
+
+func TestDecryptSecretKeySpec(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	// Java seals SecretKeySpec of AES key 000102030405060708090a0b0c0d0e0f as is, not as KeyRep.
+	spec, err := hex.DecodeString("aced00057372001f6a617661782e63727970746f2e737065632e5365637265744b6579537065635b470b66e230614d0200024c0009616c676f726974686d7400124c6a6176612f6c616e672f537472696e673b5b00036b65797400025b427870740003414553757200025b42acf317f8060854e0020000787000000010000102030405060708090a0b0c0d0e0f") // nolint
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	esk, err := encryptSecurityKey(rand.Reader, spec, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ske, err := SecurityKeyEntry{EncryptedSecurityKey: esk}.Decrypt(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	if ske.Algorithm != "AES" || ske.Format != "RAW" || !bytes.Equal(ske.SecurityKey, expected) {
+		t.Errorf("unexpected security key entry %+v", ske)
+	}
+}
+
+func TestLoadDetectsStoreType(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	tests := []struct {
+		name      string
+		file      string
+		storeType int
+	}{
+		{"jks", "./testdata/keystore.jks", JDKStoreType},
+		{"pkcs12", "./testdata/keystore.p12", PKCS12StoreType},
+	}
+
+	for _, tt := range tests {
+		data, err := ioutil.ReadFile(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ks := New()
+		if err := ks.Load(bytes.NewReader(data), password); err != nil {
+			t.Fatalf("load %s: %s", tt.name, err)
+		}
+
+		if ks.StoreType() != tt.storeType {
+			t.Errorf("unexpected store type of %s: %d", tt.name, ks.StoreType())
+		}
+	}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	if ks.StoreType() != JCEKSStoreType {
+		t.Errorf("unexpected store type: %d", ks.StoreType())
+	}
+}
+
+func TestLoadUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	bks := []byte{0, 0, 0, 2, 0, 0, 0, 20}
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(bks), []byte("password")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("unexpected error for bks keystore: %v", err)
+	}
+
+	unknown := []byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 2}
+
+	var formatErr ErrUnknownFormat
+
+	err := ks.Load(bytes.NewReader(unknown), []byte("password"))
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("unexpected error for unknown keystore: %v", err)
+	}
+
+	if !bytes.Equal(formatErr.Magic, unknown[:4]) {
+		t.Errorf("unexpected magic: %x", formatErr.Magic)
+	}
+}
+
 func readPrivateKey(t *testing.T) []byte {
 	t.Helper()
 
@@ -334,3 +595,19 @@ func readCertificate(t *testing.T) []byte {
 
 	return b.Bytes
 }
+
+// readKeytoolKeyStore returns keystore generated by testdata/keytool.sh, the test is skipped if it is not generated.
+func readKeytoolKeyStore(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Join("./testdata", name))
+	if os.IsNotExist(err) {
+		t.Skipf("%s is not generated, run testdata/keytool.sh with Java keytool", name)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
@@ -0,0 +1,169 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLoadLimits(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	for _, name := range []string{"./testdata/keystore_keypass.jks", "./testdata/keystore.p12"} {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, option := range []LoadOption{
+			WithMaxEntries(0),
+			WithMaxEntries(1),
+			WithMaxCertificateSize(4096),
+			WithMaxKeySize(4096),
+			WithMaxSize(int64(len(data))),
+		} {
+			ks := New()
+			if err := ks.Load(bytes.NewReader(data), password, option); err != nil {
+				t.Errorf("%s: unexpected error within limits: %s", name, err)
+			}
+		}
+
+		for i, option := range []LoadOption{
+			WithMaxCertificateSize(16),
+			WithMaxKeySize(16),
+			WithMaxSize(int64(len(data) - 1)),
+		} {
+			ks := New()
+			if err := ks.Load(bytes.NewReader(data), password, option); !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("%s: unexpected error exceeding %d limit: %v", name, i, err)
+			}
+		}
+	}
+
+	ks := New(WithOrderedAliases())
+
+	for _, alias := range []string{"ca1", "ca2"} {
+		tce := TrustedCertificateEntry{Certificate: Certificate{Type: "X509", Content: readCertificate(t)}}
+		if err := ks.SetTrustedCertificateEntry(alias, tce); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDecoder(bytes.NewReader(buf.Bytes()), password, WithMaxEntries(1)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("unexpected error exceeding number of entries: %v", err)
+	}
+}
+
+func TestLoadSealedSecurityKeyLimit(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	if err := ks.SetSecurityKeyEntry("ske-alias", SecurityKeyEntry{
+		SecurityKey: bytes.Repeat([]byte{1}, 64*1024),
+		Algorithm:   "HmacSHA256",
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	r := bytes.NewReader(data)
+
+	loaded := New()
+	if err := loaded.Load(r, password, WithMaxKeySize(1024)); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("unexpected error exceeding sealed key size: %v", err)
+	}
+
+	if read := len(data) - r.Len(); read > len(data)/2 {
+		t.Errorf("read %d of %d bytes, reading must stop after the limit", read, len(data))
+	}
+
+	if err := loaded.Load(bytes.NewReader(data), password, WithMaxKeySize(len(data))); err != nil {
+		t.Errorf("unexpected error within limit: %v", err)
+	}
+}
+
+func TestLoadIterationLimit(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	data, err := ioutil.ReadFile("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mac is checked before the keys and encrypted contents
+	for _, options := range [][]LoadOption{
+		{WithMaxIterations(1000)},
+		{WithMaxIterations(1000), WithoutIntegrityCheck()},
+	} {
+		loaded := New()
+		if err := loaded.Load(bytes.NewReader(data), password, options...); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("unexpected error exceeding pkcs12 iteration count: %v", err)
+		}
+	}
+
+	ks := New(WithStoreType(JCEKSStoreType))
+	if err := ks.SetSecurityKeyEntry("ske", SecurityKeyEntry{
+		SecurityKey: []byte("0123456789abcdef"),
+		Algorithm:   "AES",
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetPrivateKeyEntry("pke", PrivateKeyEntry{
+		PrivateKey:       readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+
+	for _, option := range []LoadOption{WithMaxIterations(0), WithMaxIterations(jceIterations)} {
+		if err := loaded.Load(bytes.NewReader(buf.Bytes()), password, option); err != nil {
+			t.Errorf("unexpected error within iteration count limit: %v", err)
+		}
+	}
+
+	err = loaded.Load(bytes.NewReader(buf.Bytes()), password, WithMaxIterations(jceIterations-1))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("unexpected error exceeding jceks iteration count: %v", err)
+	}
+
+	ks.DeleteEntry("pke")
+	buf.Reset()
+
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDecoder(bytes.NewReader(buf.Bytes()), password, WithMaxIterations(jceIterations-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := d.Next(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("unexpected error exceeding security key iteration count: %v", err)
+	}
+}
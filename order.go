@@ -0,0 +1,72 @@
+package keystore
+
+import "sort"
+
+// entryOrder keeps positions of the keystore entries, entries read by Load get positions in the keystore order
+// and entries added later are placed after them in insertion order.
+type entryOrder struct {
+	next     uint64
+	position map[string]uint64
+}
+
+func newEntryOrder() *entryOrder {
+	return &entryOrder{position: make(map[string]uint64)}
+}
+
+// add places alias after the existing ones unless it already has a position.
+func (o *entryOrder) add(alias string) {
+	if _, ok := o.position[alias]; ok {
+		return
+	}
+
+	o.position[alias] = o.next
+	o.next++
+}
+
+func (o *entryOrder) delete(alias string) {
+	delete(o.position, alias)
+}
+
+func (o *entryOrder) clone() *entryOrder {
+	c := &entryOrder{next: o.next, position: make(map[string]uint64, len(o.position))}
+
+	for alias, position := range o.position {
+		c.position[alias] = position
+	}
+
+	return c
+}
+
+// sort orders aliases by their positions.
+func (o *entryOrder) sort(aliases []string) {
+	sort.Slice(aliases, func(i, j int) bool {
+		return o.position[aliases[i]] < o.position[aliases[j]]
+	})
+}
+
+// setEntry adds the entry by the converted alias keeping the position of the replaced entry.
+func (ks KeyStore) setEntry(alias string, entry interface{}) {
+	ks.m[alias] = entry
+
+	if ks.order != nil {
+		ks.order.add(alias)
+	}
+}
+
+// storeAliases returns aliases in the order entries are written by Store:
+// alphabetically if keystore created using WithOrderedAliases option, otherwise by their positions.
+func (ks KeyStore) storeAliases() []string {
+	aliases := make([]string, 0, len(ks.m))
+	for alias := range ks.m {
+		aliases = append(aliases, alias)
+	}
+
+	switch {
+	case ks.ordered:
+		sort.Strings(aliases)
+	case ks.order != nil:
+		ks.order.sort(aliases)
+	}
+
+	return aliases
+}
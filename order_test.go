@@ -0,0 +1,195 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoundTripIdentical(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"keystore.jks", "keystore_keypass.jks"} {
+		data, err := ioutil.ReadFile("./testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ks := New()
+		if err := ks.Load(bytes.NewReader(data), []byte("password")); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := ks.Store(&buf, []byte("password")); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%s: stored keystore differs from the loaded one", name)
+		}
+	}
+}
+
+func TestRoundTripIdenticalVersion1(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	creationTime := time.Unix(1600000000, 0)
+	tce := TrustedCertificateEntry{
+		CreationTime: creationTime,
+		Certificate:  Certificate{Type: "X.509", Content: readCertificate(t)},
+	}
+
+	md, err := newKeyStoreDigest(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	// version 1 keystore as written by old Java versions, certificates have no type
+	kse := keyStoreEncoder{w: &buf, md: md, version: version01}
+
+	for _, err := range []error{
+		kse.writeUint32(jksmagic),
+		kse.writeUint32(version01),
+		kse.writeUint32(2),
+		kse.writeTrustedCertificateEntry("b", tce),
+		kse.writeTrustedCertificateEntry("a", tce),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := kse.writeBytes(kse.md.Sum(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored bytes.Buffer
+	if err := ks.Store(&stored, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stored.Bytes(), buf.Bytes()) {
+		t.Error("stored version 1 keystore differs from the loaded one")
+	}
+
+	tce.Certificate.Type = "PEM"
+	if err := ks.SetTrustedCertificateEntry("c", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.Store(ioutil.Discard, password); !errors.Is(err, ErrUnsupportedEntryType) {
+		t.Errorf("unexpected error storing certificate without type: %v", err)
+	}
+
+	tce.Certificate.Type = "X509"
+	if err := ks.SetTrustedCertificateEntry("c", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	stored.Reset()
+
+	if err := ks.Store(&stored, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New(WithInsertionOrderedAliases())
+	if err := loaded.Load(bytes.NewReader(stored.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.version != version01 || !reflect.DeepEqual(loaded.Aliases(), []string{"b", "a", "c"}) {
+		t.Errorf("unexpected version %d or aliases %v", loaded.version, loaded.Aliases())
+	}
+}
+
+func TestInsertionOrder(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	ks := New(WithStoreType(JCEKSStoreType), WithInsertionOrderedAliases())
+
+	if err := ks.SetSecurityKeyEntry("z-ske", SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetPrivateKeyEntry("a-pke", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetTrustedCertificateEntry("m-tce", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"z-ske", "a-pke", "m-tce"}
+	if !reflect.DeepEqual(ks.Aliases(), want) {
+		t.Errorf("unexpected aliases order %v", ks.Aliases())
+	}
+
+	var first bytes.Buffer
+	if err := ks.Store(&first, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New(WithInsertionOrderedAliases())
+	if err := loaded.Load(bytes.NewReader(first.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.Aliases(), want) {
+		t.Errorf("unexpected aliases order after load %v", loaded.Aliases())
+	}
+
+	var second bytes.Buffer
+	if err := loaded.Store(&second, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("stored keystore differs from the loaded one")
+	}
+
+	// Replaced entries keep their positions, new ones are added to the end.
+	if err := loaded.SetTrustedCertificateEntry("z-ske", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded.DeleteEntry("a-pke")
+
+	if err := loaded.SetTrustedCertificateEntry("a-pke", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"z-ske", "m-tce", "a-pke"}; !reflect.DeepEqual(loaded.Aliases(), want) {
+		t.Errorf("unexpected aliases order after changes %v", loaded.Aliases())
+	}
+}
@@ -0,0 +1,144 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4/jserial"
+)
+
+// PasswordFromRunes returns UTF-8 encoded password which is accepted by keystore functions.
+// Passwords are converted to UTF-16 as Java does, so non-ASCII passwords are compatible with keytool.
+// Password slices passed to keystore functions which are not valid UTF-8 are read byte by byte as ISO-8859-1
+// characters as older versions of the library did, so such passwords keep opening existing keystores.
+// It is strongly recommended to fill both password slices with zero after usage.
+func PasswordFromRunes(password []rune) []byte {
+	n := 0
+
+	for _, r := range password {
+		if size := utf8.RuneLen(r); size > 0 {
+			n += size
+		} else {
+			n += utf8.RuneLen(utf8.RuneError)
+		}
+	}
+
+	result := make([]byte, n)
+
+	offset := 0
+	for _, r := range password {
+		offset += utf8.EncodeRune(result[offset:], r)
+	}
+
+	return result
+}
+
+// PasswordFromString returns UTF-8 encoded password which is accepted by keystore functions.
+// Prefer PasswordFromRunes for secrets as strings can't be filled with zero after usage.
+func PasswordFromString(password string) []byte {
+	return []byte(password)
+}
+
+// ChangeEntryPassword decrypts PrivateKeyEntry or SecurityKeyEntry by the alias with oldPassword
+// and encrypts it again with newPassword using the key protector of the keystore type.
+// Other fields of the entry are kept as is.
+// It is strongly recommended to fill password slices with zero after usage.
+func (ks KeyStore) ChangeEntryPassword(alias string, oldPassword, newPassword []byte) error {
+	if utf8.RuneCount(newPassword) < minPasswordLen {
+		return fmt.Errorf("password must be at least %d characters: %w", minPasswordLen, ErrShortPassword)
+	}
+
+	alias = ks.convertAlias(alias)
+
+	e, ok := ks.m[alias]
+	if !ok {
+		return ErrEntryNotFound
+	}
+
+	switch typedEntry := e.(type) {
+	case PrivateKeyEntry:
+		epk, err := reencryptPrivateKey(ks.storeType, typedEntry.encryptedPrivateKey, oldPassword, newPassword)
+		if err != nil {
+			return err
+		}
+
+		typedEntry.encryptedPrivateKey = epk
+		ks.m[alias] = typedEntry
+	case SecurityKeyEntry:
+		esk, err := reencryptSecurityKey(typedEntry.EncryptedSecurityKey, oldPassword, newPassword)
+		if err != nil {
+			return err
+		}
+
+		typedEntry.EncryptedSecurityKey = esk
+		ks.m[alias] = typedEntry
+	default:
+		return ErrWrongEntryType
+	}
+
+	return nil
+}
+
+// ChangeStorePassword reads the keystore from r using oldPassword and writes it into w signed with newPassword.
+// Keys of PKCS12 keystores are protected by the store password, so they are re-encrypted with newPassword too.
+// Keys of JKS and JCEKS keystores keep their passwords, use ChangeEntryPassword to change them.
+// It is strongly recommended to fill password slices with zero after usage.
+func ChangeStorePassword(r io.Reader, w io.Writer, oldPassword, newPassword []byte, options ...Option) error {
+	ks := New(options...)
+	if err := ks.Load(r, oldPassword); err != nil {
+		return fmt.Errorf("load keystore: %w", err)
+	}
+
+	if ks.storeType == PKCS12StoreType {
+		for _, alias := range ks.Aliases() {
+			if !ks.IsPrivateKeyEntry(alias) {
+				continue
+			}
+
+			if err := ks.ChangeEntryPassword(alias, oldPassword, newPassword); err != nil {
+				return fmt.Errorf("change password of entry %q: %w", alias, err)
+			}
+		}
+	}
+
+	if err := ks.Store(w, newPassword); err != nil {
+		return fmt.Errorf("store keystore: %w", err)
+	}
+
+	return nil
+}
+
+func reencryptPrivateKey(storeType int, encryptedKey, oldPassword, newPassword []byte) ([]byte, error) {
+	plainKey, err := decrypt(encryptedKey, oldPassword)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer zeroing(plainKey)
+
+	epk, err := encryptPrivateKey(storeType, plainKey, newPassword)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt private key: %w", err)
+	}
+
+	return epk, nil
+}
+
+func reencryptSecurityKey(
+	encrypted jserial.EncryptedSecurityKey, oldPassword, newPassword []byte,
+) (jserial.EncryptedSecurityKey, error) {
+	serializedKey, err := decryptSecurityKey(encrypted, oldPassword)
+	if err != nil {
+		return jserial.EncryptedSecurityKey{}, err
+	}
+
+	defer zeroing(serializedKey)
+
+	if _, err := decodeKeyRep(serializedKey); err != nil {
+		return jserial.EncryptedSecurityKey{}, err
+	}
+
+	return encryptSecurityKey(rand.Reader, serializedKey, newPassword)
+}
@@ -0,0 +1,320 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChangeEntryPassword(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	keyPassword := []byte("keypassword")
+	newKeyPassword := []byte("newkeypassword")
+
+	f, err := os.Open("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatalf("open test data keystore file: %s", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatalf("close test data keystore file: %s", err)
+		}
+	}()
+
+	ks := New()
+	if err := ks.Load(f, password); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPKE, err := ks.GetPrivateKeyEntry("alias", keyPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.ChangeEntryPassword("alias", password, newKeyPassword); err == nil {
+		t.Error("should fail with wrong old password")
+	}
+
+	if err := ks.ChangeEntryPassword("alias", keyPassword, newKeyPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.GetPrivateKeyEntry("alias", keyPassword); err == nil {
+		t.Error("should fail with old password")
+	}
+
+	actualPKE, err := ks.GetPrivateKeyEntry("alias", newKeyPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actualPKE, expectedPKE) {
+		t.Error("private key entry changed after password change")
+	}
+
+	if err := ks.ChangeEntryPassword("nonExistentAlias", keyPassword, newKeyPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("unexpected error for non existent alias: %v", err)
+	}
+
+	if err := ks.ChangeEntryPassword("alias", newKeyPassword, []byte("short")); !errors.Is(err, ErrShortPassword) {
+		t.Errorf("unexpected error for short password: %v", err)
+	}
+}
+
+func TestChangeEntryPasswordSecurityKey(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	newPassword := []byte("newpassword")
+
+	ks := New(WithStoreType(JCEKSStoreType))
+
+	ske := SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}
+
+	if err := ks.SetSecurityKeyEntry("ske-alias", ske, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.ChangeEntryPassword("ske-alias", password, newPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	actualSKE, err := ks.GetSecurityKeyEntry("ske-alias", newPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(actualSKE.SecurityKey, ske.SecurityKey) || actualSKE.Algorithm != ske.Algorithm {
+		t.Errorf("unexpected security key entry: %+v", actualSKE)
+	}
+}
+
+func TestChangeStorePassword(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	newPassword := []byte("newpassword")
+
+	f, err := os.Open("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatalf("open test data keystore file: %s", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatalf("close test data keystore file: %s", err)
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := ChangeStorePassword(f, &buf, password, newPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New()
+
+	if err := ks.Load(bytes.NewReader(buf.Bytes()), password); err == nil {
+		t.Error("should fail to load with old password")
+	}
+
+	if err := ks.Load(bytes.NewReader(buf.Bytes()), newPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.GetPrivateKeyEntry("alias", newPassword); err != nil {
+		t.Errorf("private key should be encrypted with the new password: %s", err)
+	}
+}
+
+func TestLoadLegacyPasswordEncoding(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("pässwörd")
+
+	// older versions encoded every password byte as a character, i.e. as the password read as ISO-8859-1
+	legacyRunes := make([]rune, 0, len(password))
+	for _, b := range password {
+		legacyRunes = append(legacyRunes, rune(b))
+	}
+
+	legacyPassword := PasswordFromRunes(legacyRunes)
+
+	f, err := os.Open("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	source := New()
+	if err := source.Load(f, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	pke, err := source.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tce := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	for _, storeType := range []int{JDKStoreType, JCEKSStoreType, PKCS12StoreType} {
+		legacy := New(WithStoreType(storeType))
+		if err := legacy.SetTrustedCertificateEntry("tce", tce); err != nil {
+			t.Fatal(err)
+		}
+
+		if storeType == JDKStoreType {
+			if err := legacy.SetPrivateKeyEntry("pke", pke, legacyPassword); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := legacy.Store(&buf, legacyPassword); err != nil {
+			t.Fatal(err)
+		}
+
+		if verified, err := Verify(bytes.NewReader(buf.Bytes()), password); err != nil || !verified {
+			t.Errorf("%d: keystore with legacy password encoding is not verified: %v", storeType, err)
+		}
+
+		if verified, err := Verify(bytes.NewReader(buf.Bytes()), []byte("pässwört")); err != nil || verified {
+			t.Errorf("%d: keystore is verified with wrong password: %v", storeType, err)
+		}
+
+		// PKCS12 safe contents are encrypted with PBES2 which takes UTF-8 password as is
+		if storeType == PKCS12StoreType {
+			continue
+		}
+
+		ks := New()
+		if err := ks.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+			t.Fatalf("%d: %v", storeType, err)
+		}
+
+		if storeType == JDKStoreType {
+			actualPKE, err := ks.GetPrivateKeyEntry("pke", password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(actualPKE.PrivateKey, pke.PrivateKey) {
+				t.Error("unexpected private key")
+			}
+		}
+
+		// Store writes the keystore with the current password encoding
+		buf.Reset()
+
+		if err := ks.Store(&buf, password); err != nil {
+			t.Fatal(err)
+		}
+
+		if verified, err := Verify(bytes.NewReader(buf.Bytes()), legacyPassword); err != nil || verified {
+			t.Errorf("%d: keystore is written with legacy password encoding: %v", storeType, err)
+		}
+	}
+}
+
+func TestPasswordCharacters(t *testing.T) {
+	t.Parallel()
+
+	ske := SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}
+
+	// 8 bytes, but 4 characters
+	if err := New().Store(&bytes.Buffer{}, []byte("ääää")); !errors.Is(err, ErrShortPassword) {
+		t.Errorf("unexpected error for short non-ascii password: %v", err)
+	}
+
+	// 6 characters, but 8 bytes
+	jks := New()
+	if err := jks.SetTrustedCertificateEntry("tce", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jks.Store(&bytes.Buffer{}, []byte("pässwö")); err != nil {
+		t.Errorf("unexpected error for non-ascii password: %v", err)
+	}
+
+	jceks := New(WithStoreType(JCEKSStoreType))
+	if err := jceks.SetSecurityKeyEntry("ske", ske, []byte("pässwörd")); !errors.Is(err, ErrNonASCIIPassword) {
+		t.Errorf("unexpected error for non-ascii security key password: %v", err)
+	}
+
+	if err := jceks.SetSecurityKeyEntry("ske", ske, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := jceks.ChangeEntryPassword("ske", []byte("password"), []byte("pässwörd"))
+	if !errors.Is(err, ErrNonASCIIPassword) {
+		t.Errorf("unexpected error for non-ascii new security key password: %v", err)
+	}
+
+	pke := PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+		},
+	}
+
+	if err := jceks.SetPrivateKeyEntry("pke", pke, []byte("pässwörd")); !errors.Is(err, ErrNonASCIIPassword) {
+		t.Errorf("unexpected error for non-ascii private key password: %v", err)
+	}
+}
+
+func TestISO88591Password(t *testing.T) {
+	t.Parallel()
+
+	latin1 := []byte{0xe4, 'a', 'b', 'c', 'd', 'e'}
+	utf8 := []byte("äabcde")
+
+	pke := PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+		},
+	}
+
+	for _, storeType := range []int{JDKStoreType, PKCS12StoreType} {
+		for _, passwords := range [][2][]byte{{latin1, utf8}, {utf8, latin1}} {
+			ks := New(WithStoreType(storeType))
+			if err := ks.SetPrivateKeyEntry("a", pke, passwords[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			actualPKE, err := ks.GetPrivateKeyEntry("a", passwords[1])
+			if err != nil {
+				t.Errorf("%d: key set with %x is not decrypted with %x: %v", storeType, passwords[0], passwords[1], err)
+
+				continue
+			}
+
+			if !bytes.Equal(actualPKE.PrivateKey, pke.PrivateKey) {
+				t.Errorf("%d: unexpected private key", storeType)
+			}
+		}
+	}
+}
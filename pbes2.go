@@ -0,0 +1,435 @@
+package keystore
+
+import ( //nolint:gci
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec,gci
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec,gci
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	pbes2Iterations = 10000
+	pbes2SaltLen    = 20
+	pbes2KeyLen     = 32
+	macIterations   = 10000
+	macSaltLen      = 20
+)
+
+// PKCS#12 key derivation purposes, see RFC 7292 appendix B.3.
+const (
+	pkcs12KeyMaterial byte = 1
+	pkcs12IVMaterial  byte = 2
+	pkcs12MACMaterial byte = 3
+)
+
+var (
+	pbes2Oid                   = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 5, 13})
+	pbkdf2Oid                  = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 5, 12})
+	hmacWithSHA1Oid            = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 2, 7})
+	hmacWithSHA256Oid          = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 2, 9})
+	aes128CBCOid               = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 2})
+	aes192CBCOid               = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 22})
+	aes256CBCOid               = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 42})
+	pbeWithSHAAnd3KeyDESCBCOid = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 3})
+	sha1Oid                    = asn1.ObjectIdentifier([]int{1, 3, 14, 3, 2, 26})
+	sha256Oid                  = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 2, 1})
+)
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// pbkdf2 derives key as described in RFC 8018 section 5.2.
+func pbkdf2(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt) //nolint:errcheck
+		byteOrder.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:]) //nolint:errcheck
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u) //nolint:errcheck
+			u = u[:0]
+			u = prf.Sum(u)
+
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
+
+// pkcs12KDF derives key as described in RFC 7292 appendix B.2.
+// The password must be BMPString encoded including two trailing zero bytes.
+func pkcs12KDF(h func() hash.Hash, password, salt []byte, id byte, iterations, size int) []byte {
+	md := h()
+	u := md.Size()
+	v := md.BlockSize()
+
+	d := bytes.Repeat([]byte{id}, v)
+	s := fillBlocks(salt, v)
+	p := fillBlocks(password, v)
+	i := append(s, p...) //nolint:gocritic
+
+	result := make([]byte, 0, (size+u-1)/u*u)
+
+	for len(result) < size {
+		md.Reset()
+		md.Write(d) //nolint:errcheck
+		md.Write(i) //nolint:errcheck
+		a := md.Sum(nil)
+
+		for r := 1; r < iterations; r++ {
+			md.Reset()
+			md.Write(a) //nolint:errcheck
+			a = md.Sum(a[:0])
+		}
+
+		result = append(result, a...)
+
+		b := fillBlocks(a, v)[:v]
+		for j := 0; j < len(i); j += v {
+			addBlocks(i[j:j+v], b)
+		}
+	}
+
+	return result[:size]
+}
+
+// fillBlocks concatenates copies of data up to the nearest multiple of v bytes.
+func fillBlocks(data []byte, v int) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	result := make([]byte, v*((len(data)+v-1)/v))
+	for i := range result {
+		result[i] = data[i%len(data)]
+	}
+
+	return result
+}
+
+// addBlocks sets block to (block + b + 1) mod 2^(8*len(block)).
+func addBlocks(block, b []byte) {
+	carry := 1
+	for k := len(block) - 1; k >= 0; k-- {
+		sum := int(block[k]) + int(b[k]) + carry
+		block[k] = byte(sum)
+		carry = sum >> 8
+	}
+}
+
+// bmpPassword converts password into BMPString with trailing zeros as PKCS#12 key derivation expects.
+func bmpPassword(password []byte) []byte {
+	return append(passwordBytes(password), 0, 0)
+}
+
+func newPBES2AlgorithmIdentifier(rand io.Reader) (pkix.AlgorithmIdentifier, []byte, []byte, error) {
+	salt := make([]byte, pbes2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, nil, fmt.Errorf("read random salt: %w", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, nil, fmt.Errorf("read random iv: %w", err)
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbes2Iterations,
+		KeyLength:      pbes2KeyLen,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: hmacWithSHA256Oid, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, nil, fmt.Errorf("marshal pbkdf2 parameters: %w", err)
+	}
+
+	encodedIV, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, nil, fmt.Errorf("marshal iv: %w", err)
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: pbkdf2Oid, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: aes256CBCOid, Parameters: asn1.RawValue{FullBytes: encodedIV}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, nil, fmt.Errorf("marshal pbes2 parameters: %w", err)
+	}
+
+	algo := pkix.AlgorithmIdentifier{Algorithm: pbes2Oid, Parameters: asn1.RawValue{FullBytes: params}}
+
+	return algo, salt, iv, nil
+}
+
+// pbeIterations returns the iteration count of password based encryption algorithm. Zero is returned
+// for algorithms without it, e.g. JKS key protector, and for parameters which can't be decoded,
+// they are reported when the data is decrypted.
+func pbeIterations(algo pkix.AlgorithmIdentifier) int {
+	switch {
+	case algo.Algorithm.Equal(pbes2Oid):
+		var params pbes2Params
+		if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &params); err != nil {
+			return 0
+		}
+
+		var kdfParams pbkdf2Params
+		if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+			return 0
+		}
+
+		return kdfParams.IterationCount
+	case algo.Algorithm.Equal(pbeWithSHAAnd3KeyDESCBCOid), algo.Algorithm.Equal(jcePrivateKeyAlgorithmOid):
+		return pbeParamsIterations(algo.Parameters.FullBytes)
+	default:
+		return 0
+	}
+}
+
+// pbeParamsIterations returns the iteration count of encoded PBE parameters or zero if they can't be decoded.
+func pbeParamsIterations(encodedParams []byte) int {
+	var params pbeParams
+	if _, err := asn1.Unmarshal(encodedParams, &params); err != nil {
+		return 0
+	}
+
+	return params.Iterations
+}
+
+// keyIterations returns the iteration count of the encrypted private key, see pbeIterations.
+func keyIterations(encryptedKey []byte) int {
+	var info keyInfo
+	if _, err := asn1.Unmarshal(encryptedKey, &info); err != nil {
+		return 0
+	}
+
+	return pbeIterations(info.Algo)
+}
+
+// encryptPBES2 encrypts data with PBKDF2-HMAC-SHA256 and AES-256-CBC as Java's PKCS12 keystore does by default.
+func encryptPBES2(rand io.Reader, data []byte, password []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	algo, salt, iv, err := newPBES2AlgorithmIdentifier(rand)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	if normalized := utf8Password(password); normalized != nil {
+		defer zeroing(normalized)
+
+		password = normalized
+	}
+
+	key := pbkdf2(password, salt, pbes2Iterations, pbes2KeyLen, sha256.New)
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("create aes cipher: %w", err)
+	}
+
+	padded := PKCS5Padding(append([]byte(nil), data...), block.BlockSize())
+	defer zeroing(padded)
+
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	return algo, encrypted, nil
+}
+
+// decryptPBE decrypts data protected by PBES2 or legacy pbeWithSHAAnd3-KeyTripleDES-CBC algorithms.
+// PBES2 keys are derived from the password encoded into UTF-8, data encrypted by older versions
+// with the password bytes as is are decrypted too.
+func decryptPBE(algo pkix.AlgorithmIdentifier, data []byte, password []byte) ([]byte, error) {
+	normalized := utf8Password(password)
+	if normalized == nil || !algo.Algorithm.Equal(pbes2Oid) {
+		return decryptPBEWithPassword(algo, data, password)
+	}
+
+	defer zeroing(normalized)
+
+	decrypted, err := decryptPBEWithPassword(algo, data, normalized)
+	if err == nil {
+		return decrypted, nil
+	}
+
+	if legacyDecrypted, legacyErr := decryptPBEWithPassword(algo, data, password); legacyErr == nil {
+		return legacyDecrypted, nil
+	}
+
+	return nil, err
+}
+
+func decryptPBEWithPassword(algo pkix.AlgorithmIdentifier, data []byte, password []byte) ([]byte, error) {
+	var (
+		block cipher.Block
+		iv    []byte
+		err   error
+	)
+
+	switch {
+	case algo.Algorithm.Equal(pbes2Oid):
+		block, iv, err = pbes2Cipher(algo.Parameters.FullBytes, password)
+	case algo.Algorithm.Equal(pbeWithSHAAnd3KeyDESCBCOid):
+		block, iv, err = pkcs12TripleDESCipher(algo.Parameters.FullBytes, password)
+	default:
+		err = ErrUnsupportedAlgorithm{OID: algo.Algorithm}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("got encrypted data of invalid length")
+	}
+
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+
+	return PKCS5Unpadding(decrypted, block.BlockSize())
+}
+
+func pbes2Cipher(encodedParams []byte, password []byte) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(encodedParams, &params); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal pbes2 parameters: %w", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(pbkdf2Oid) {
+		return nil, nil, ErrUnsupportedAlgorithm{OID: params.KeyDerivationFunc.Algorithm}
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal pbkdf2 parameters: %w", err)
+	}
+
+	var prf func() hash.Hash
+
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0, kdfParams.PRF.Algorithm.Equal(hmacWithSHA1Oid):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(hmacWithSHA256Oid):
+		prf = sha256.New
+	default:
+		return nil, nil, ErrUnsupportedAlgorithm{OID: kdfParams.PRF.Algorithm}
+	}
+
+	var keyLen int
+
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(aes128CBCOid):
+		keyLen = 16
+	case params.EncryptionScheme.Algorithm.Equal(aes192CBCOid):
+		keyLen = 24
+	case params.EncryptionScheme.Algorithm.Equal(aes256CBCOid):
+		keyLen = 32
+	default:
+		return nil, nil, ErrUnsupportedAlgorithm{OID: params.EncryptionScheme.Algorithm}
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal iv: %w", err)
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, nil, errors.New("got iv of invalid length")
+	}
+
+	if err := checkIterations(kdfParams.IterationCount); err != nil {
+		return nil, nil, err
+	}
+
+	key := pbkdf2(password, kdfParams.Salt, kdfParams.IterationCount, keyLen, prf)
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create aes cipher: %w", err)
+	}
+
+	return block, iv, nil
+}
+
+func pkcs12TripleDESCipher(encodedParams []byte, password []byte) (cipher.Block, []byte, error) {
+	params, err := decodeParams(encodedParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	key := pkcs12KDF(sha1.New, bmp, params.Salt, pkcs12KeyMaterial, params.Iterations, 24)
+	defer zeroing(key)
+
+	iv := pkcs12KDF(sha1.New, bmp, params.Salt, pkcs12IVMaterial, params.Iterations, des.BlockSize)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create triple des cipher: %w", err)
+	}
+
+	return block, iv, nil
+}
+
+// computeMAC calculates PKCS#12 HMAC over data with key derived from password converted by bmpPassword.
+func computeMAC(algorithm asn1.ObjectIdentifier, data, salt []byte, iterations int, bmp []byte) ([]byte, error) {
+	var h func() hash.Hash
+
+	switch {
+	case algorithm.Equal(sha1Oid):
+		h = sha1.New
+	case algorithm.Equal(sha256Oid):
+		h = sha256.New
+	default:
+		return nil, ErrUnsupportedAlgorithm{OID: algorithm}
+	}
+
+	if err := checkIterations(iterations); err != nil {
+		return nil, err
+	}
+
+	key := pkcs12KDF(h, bmp, salt, pkcs12MACMaterial, iterations, h().Size())
+	defer zeroing(key)
+
+	mac := hmac.New(h, key)
+	mac.Write(data) //nolint:errcheck
+
+	return mac.Sum(nil), nil
+}
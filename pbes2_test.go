@@ -0,0 +1,106 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	t.Parallel()
+
+	// RFC 6070 test vectors
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{"password", "salt", 1, 20, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"password", "salt", 4096, 20, "4b007901b765489abead49d926f721d065a429c1"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25,
+			"3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+	}
+
+	for _, tt := range tests {
+		got := pbkdf2([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen, sha1.New)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("pbkdf2() = %x, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestEncryptDecryptPBES2(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	plain := []byte("my_secret")
+
+	algo, encrypted, err := encryptPBES2(rand.Reader, plain, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := decryptPBE(algo, encrypted, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("decryptPBE() = %v, want %v", decrypted, plain)
+	}
+}
+
+func TestPBES2PasswordEncoding(t *testing.T) {
+	t.Parallel()
+
+	latin1 := []byte{0xe4, 'a', 'b', 'c', 'd', 'e'}
+	utf8 := []byte("äabcde")
+	plain := []byte("my_secret")
+
+	for _, passwords := range [][2][]byte{{latin1, utf8}, {utf8, latin1}} {
+		algo, encrypted, err := encryptPBES2(rand.Reader, plain, passwords[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decrypted, err := decryptPBE(algo, encrypted, passwords[1])
+		if err != nil {
+			t.Fatalf("encrypted with %x, decrypted with %x: %v", passwords[0], passwords[1], err)
+		}
+
+		if !bytes.Equal(decrypted, plain) {
+			t.Errorf("decryptPBE() = %v, want %v", decrypted, plain)
+		}
+	}
+
+	// older versions derived the key from the password bytes as is
+	algo, salt, iv, err := newPBES2AlgorithmIdentifier(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := aes.NewCipher(pbkdf2(latin1, salt, pbes2Iterations, pbes2KeyLen, sha256.New))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	padded := PKCS5Padding(append([]byte(nil), plain...), block.BlockSize())
+	legacy := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(legacy, padded)
+
+	decrypted, err := decryptPBE(algo, legacy, latin1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("decryptPBE() = %v, want %v", decrypted, plain)
+	}
+}
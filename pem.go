@@ -0,0 +1,322 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	pemPrivateKeyType          = "PRIVATE KEY"
+	pemEncryptedPrivateKeyType = "ENCRYPTED PRIVATE KEY"
+	pemRSAPrivateKeyType       = "RSA PRIVATE KEY"
+	pemECPrivateKeyType        = "EC PRIVATE KEY"
+	pemCertificateType         = "CERTIFICATE"
+	// pemAliasHeader keeps alias of the keystore entry in PEM headers.
+	pemAliasHeader = "Alias"
+)
+
+var (
+	ErrNoPEMData               = errors.New("no pem data")
+	ErrInvalidCertificateChain = errors.New("invalid certificate chain")
+	ErrUnsupportedPEMBlock     = errors.New("unsupported pem block")
+	ErrMissingKeyPassword      = errors.New("missing password of encrypted private key")
+)
+
+// PrivateKeyEntryFromPEM creates PrivateKeyEntry from PEM encoded private key and certificates.
+// Private key can be PKCS#1, PKCS#8, SEC1 EC or encrypted PKCS#8 (keyPassword is used to decrypt it),
+// it is always converted to PKCS#8 as Java expects. Certificates are ordered leaf first,
+// the leaf certificate must match the private key, other certificates must form the chain of its issuers.
+// At least the leaf certificate is required as Java's PrivateKeyEntry does, ErrNoPEMData is returned otherwise.
+// It is strongly recommended to fill password slice with zero after usage.
+func PrivateKeyEntryFromPEM(keyPEM, certsPEM, keyPassword []byte) (PrivateKeyEntry, error) {
+	pk, err := parsePEMPrivateKey(keyPEM, keyPassword)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("parse private key: %w", err)
+	}
+
+	certs, err := parsePEMCertificates(certsPEM)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("parse certificates: %w", err)
+	}
+
+	signer, err := PrivateKeyEntry{PrivateKey: pk}.Signer()
+	if err != nil {
+		return PrivateKeyEntry{}, err
+	}
+
+	chain, err := orderCertificateChain(signer.Public(), certs)
+	if err != nil {
+		return PrivateKeyEntry{}, err
+	}
+
+	pke := PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       pk,
+		CertificateChain: make([]Certificate, 0, len(chain)),
+	}
+
+	for _, cert := range chain {
+		pke.CertificateChain = append(pke.CertificateChain, Certificate{Type: x509CertificateType, Content: cert.Raw})
+	}
+
+	return pke, nil
+}
+
+// TrustedCertificateEntriesFromPEM creates TrustedCertificateEntry for every certificate of PEM bundle.
+// Entries are mapped by the alias from "Alias" PEM header or by SHA-1 fingerprint of the certificate.
+func TrustedCertificateEntriesFromPEM(bundle []byte) (map[string]TrustedCertificateEntry, error) {
+	entries := make(map[string]TrustedCertificateEntry)
+
+	for i, rest := 0, bundle; ; i++ {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != pemCertificateType {
+			return nil, fmt.Errorf("got %d block of type %s: %w", i, block.Type, ErrUnsupportedPEMBlock)
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parse %d certificate: %w", i, err)
+		}
+
+		alias, ok := block.Headers[pemAliasHeader]
+		if !ok {
+			fingerprint := sha1.Sum(block.Bytes) //nolint:gosec
+			alias = hex.EncodeToString(fingerprint[:])
+		}
+
+		if _, ok := entries[alias]; ok {
+			return nil, fmt.Errorf("got duplicate alias %q of %d certificate", alias, i)
+		}
+
+		entries[alias] = TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate:  Certificate{Type: x509CertificateType, Content: block.Bytes},
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoPEMData
+	}
+
+	return entries, nil
+}
+
+// PrivateKeyPEM returns PKCS#8 PEM encoded private key of PrivateKeyEntry decrypted with the password.
+// If pemPassword is not empty the key is encrypted with PBES2 (PBKDF2 with HMAC-SHA256 and AES-256-CBC).
+// It is strongly recommended to fill password slices with zero after usage.
+func (ks KeyStore) PrivateKeyPEM(alias string, password, pemPassword []byte) ([]byte, error) {
+	pke, err := ks.GetPrivateKeyEntry(alias, password)
+	if err != nil {
+		return nil, err
+	}
+
+	defer zeroing(pke.PrivateKey)
+
+	if len(pemPassword) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKeyType, Bytes: pke.PrivateKey}), nil
+	}
+
+	encrypted, err := encryptPKCS12Key(rand.Reader, pke.PrivateKey, pemPassword)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt private key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemEncryptedPrivateKeyType, Bytes: encrypted}), nil
+}
+
+// CertificateChainPEM returns PEM encoded certificate chain of PrivateKeyEntry, the leaf certificate goes first.
+func (ks KeyStore) CertificateChainPEM(alias string) ([]byte, error) {
+	chain, err := ks.GetPrivateKeyEntryCertificateChain(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	for i, cert := range chain {
+		if err := encodeCertificatePEM(&buf, cert, nil); err != nil {
+			return nil, fmt.Errorf("encode %d certificate: %w", i, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// TrustedCertificatesPEM returns PEM bundle of all TrustedCertificateEntry sorted by alias.
+// The alias of every entry is kept in "Alias" PEM header.
+func (ks KeyStore) TrustedCertificatesPEM() ([]byte, error) {
+	entries := make(map[string]TrustedCertificateEntry)
+	aliases := make([]string, 0, len(ks.m))
+
+	for alias, e := range ks.m {
+		if tce, ok := e.(TrustedCertificateEntry); ok {
+			entries[alias] = tce
+			aliases = append(aliases, alias)
+		}
+	}
+
+	sort.Strings(aliases)
+
+	var buf bytes.Buffer
+
+	for _, alias := range aliases {
+		tce := entries[alias]
+
+		if err := encodeCertificatePEM(&buf, tce.Certificate, map[string]string{pemAliasHeader: alias}); err != nil {
+			return nil, fmt.Errorf("encode certificate %q: %w", alias, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeCertificatePEM(buf *bytes.Buffer, cert Certificate, headers map[string]string) error {
+	if !isX509Certificate(cert) {
+		return fmt.Errorf("got certificate of type %s: %w", cert.Type, ErrUnsupportedCertificateType)
+	}
+
+	return pem.Encode(buf, &pem.Block{Type: pemCertificateType, Headers: headers, Bytes: cert.Content})
+}
+
+// parsePEMPrivateKey returns PKCS#8 encoded private key from the first PEM block.
+func parsePEMPrivateKey(data, password []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMData
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+
+	switch block.Type {
+	case pemPrivateKeyType:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case pemRSAPrivateKeyType:
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pemECPrivateKeyType:
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case pemEncryptedPrivateKeyType:
+		key, err = parseEncryptedPKCS8PrivateKey(block.Bytes, password)
+	default:
+		return nil, fmt.Errorf("got block of type %s: %w", block.Type, ErrUnsupportedPEMBlock)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.MarshalPKCS8PrivateKey(key)
+}
+
+func parseEncryptedPKCS8PrivateKey(der, password []byte) (interface{}, error) {
+	if len(password) == 0 {
+		return nil, ErrMissingKeyPassword
+	}
+
+	var encrypted keyInfo
+	if err := unmarshalStrict(der, &encrypted); err != nil {
+		return nil, fmt.Errorf("unmarshal encrypted private key: %w", err)
+	}
+
+	plain, err := decryptPBE(encrypted.Algo, encrypted.PrivateKey, password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer zeroing(plain)
+
+	return x509.ParsePKCS8PrivateKey(plain)
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for i, rest := 0, data; ; i++ {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != pemCertificateType {
+			return nil, fmt.Errorf("got %d block of type %s: %w", i, block.Type, ErrUnsupportedPEMBlock)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse %d certificate: %w", i, err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrNoPEMData
+	}
+
+	return certs, nil
+}
+
+// orderCertificateChain puts the certificate of the public key first and follows its issuers.
+func orderCertificateChain(public crypto.PublicKey, certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	rest := make([]*x509.Certificate, 0, len(certs))
+
+	var current *x509.Certificate
+
+	for _, cert := range certs {
+		key, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+		if current == nil && ok && key.Equal(public) {
+			current = cert
+
+			continue
+		}
+
+		rest = append(rest, cert)
+	}
+
+	if current == nil {
+		return nil, ErrKeyMismatch
+	}
+
+	chain := []*x509.Certificate{current}
+
+	for len(rest) > 0 {
+		found := -1
+
+		for i, cert := range rest {
+			if bytes.Equal(cert.RawSubject, current.RawIssuer) {
+				found = i
+
+				break
+			}
+		}
+
+		if found < 0 {
+			return nil, fmt.Errorf("certificate %q is not an issuer in the chain: %w",
+				rest[0].Subject, ErrInvalidCertificateChain)
+		}
+
+		current = rest[found]
+		chain = append(chain, current)
+		rest = append(rest[:found], rest[found+1:]...)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,212 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestPrivateKeyEntryFromPEM(t *testing.T) {
+	t.Parallel()
+
+	expectedKey := readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY")
+	cert := readFile(t, "./testdata/cert_pkcs12.pem")
+
+	for _, name := range []string{"./testdata/key_pkcs12.pem", "./testdata/key_pkcs12_pkcs1.pem"} {
+		pke, err := PrivateKeyEntryFromPEM(readFile(t, name), cert, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if !bytes.Equal(pke.PrivateKey, expectedKey) {
+			t.Errorf("%s: private key is not converted to pkcs8", name)
+		}
+
+		if len(pke.CertificateChain) != 1 || pke.CertificateChain[0].Type != x509CertificateType {
+			t.Errorf("%s: unexpected certificate chain: %v", name, pke.CertificateChain)
+		}
+	}
+}
+
+func TestPrivateKeyEntryFromPEMChain(t *testing.T) {
+	t.Parallel()
+
+	caCert := readFile(t, "./testdata/ca_cert.pem")
+	leafCert := readFile(t, "./testdata/leaf_cert.pem")
+	bundle := append(append([]byte{}, caCert...), leafCert...)
+
+	plain, err := PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_ec.pem"), bundle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_encrypted.pem"), bundle, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(plain.PrivateKey, encrypted.PrivateKey) {
+		t.Error("decrypted private key differs from the plain one")
+	}
+
+	if _, err := x509.ParsePKCS8PrivateKey(plain.PrivateKey); err != nil {
+		t.Errorf("private key is not pkcs8: %s", err)
+	}
+
+	chain, err := plain.Chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chain) != 2 || chain[0].Subject.CommonName != "leaf" || chain[1].Subject.CommonName != "Test CA" {
+		t.Errorf("chain is not ordered leaf first")
+	}
+
+	_, err = PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_encrypted.pem"), bundle, nil)
+	if !errors.Is(err, ErrMissingKeyPassword) {
+		t.Errorf("unexpected error without password: %v", err)
+	}
+
+	_, err = PrivateKeyEntryFromPEM(readFile(t, "./testdata/key_pkcs12.pem"), bundle, nil)
+	if !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("unexpected error for mismatched key: %v", err)
+	}
+
+	cert := readFile(t, "./testdata/cert_pkcs12.pem")
+
+	_, err = PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_ec.pem"), append(bundle, cert...), nil)
+	if !errors.Is(err, ErrInvalidCertificateChain) {
+		t.Errorf("unexpected error for unrelated certificate: %v", err)
+	}
+
+	_, err = PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_ec.pem"), nil, nil)
+	if !errors.Is(err, ErrNoPEMData) {
+		t.Errorf("unexpected error for empty certificate chain: %v", err)
+	}
+}
+
+func TestTrustedCertificateEntriesFromPEM(t *testing.T) {
+	t.Parallel()
+
+	caBlock := readPEMBlock(t, "./testdata/ca_cert.pem", "CERTIFICATE")
+	leafBlock := readPEMBlock(t, "./testdata/leaf_cert.pem", "CERTIFICATE")
+
+	bundle := pem.EncodeToMemory(&pem.Block{
+		Type:    "CERTIFICATE",
+		Headers: map[string]string{"Alias": "ca"},
+		Bytes:   caBlock,
+	})
+	bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafBlock})...)
+
+	entries, err := TrustedCertificateEntriesFromPEM(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries count: %d", len(entries))
+	}
+
+	if !bytes.Equal(entries["ca"].Certificate.Content, caBlock) {
+		t.Error("ca certificate is not found by alias header")
+	}
+
+	leaf, err := x509.ParseCertificate(leafBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for alias, tce := range entries {
+		if alias != "ca" && !bytes.Equal(tce.Certificate.Content, leaf.Raw) {
+			t.Errorf("unexpected certificate of alias %q", alias)
+		}
+	}
+
+	if _, err := TrustedCertificateEntriesFromPEM(nil); !errors.Is(err, ErrNoPEMData) {
+		t.Errorf("unexpected error for empty bundle: %v", err)
+	}
+}
+
+func TestPEMExport(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	pemPassword := []byte("pempassword")
+
+	caCert := readFile(t, "./testdata/ca_cert.pem")
+	leafCert := readFile(t, "./testdata/leaf_cert.pem")
+
+	pke, err := PrivateKeyEntryFromPEM(readFile(t, "./testdata/leaf_key_ec.pem"), append(caCert, leafCert...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New()
+
+	if err := ks.SetPrivateKeyEntry("leaf", pke, password); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alias := range []string{"ca", "other-ca"} {
+		tce := TrustedCertificateEntry{CreationTime: pke.CreationTime, Certificate: pke.CertificateChain[1]}
+		if err := ks.SetTrustedCertificateEntry(alias, tce); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chainPEM, err := ks.CertificateChainPEM("leaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keyPassword := range [][]byte{nil, pemPassword} {
+		keyPEM, err := ks.PrivateKeyPEM("leaf", password, keyPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exported, err := PrivateKeyEntryFromPEM(keyPEM, chainPEM, keyPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exported.CreationTime = pke.CreationTime
+
+		if !reflect.DeepEqual(exported, pke) {
+			t.Errorf("exported entry is not equal to the original one")
+		}
+	}
+
+	bundle, err := ks.TrustedCertificatesPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := TrustedCertificateEntriesFromPEM(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || !bytes.Equal(entries["other-ca"].Certificate.Content, pke.CertificateChain[1].Content) {
+		t.Errorf("unexpected trusted certificate entries: %v", entries)
+	}
+
+	if _, err := ks.CertificateChainPEM("ca"); !errors.Is(err, ErrWrongEntryType) {
+		t.Errorf("unexpected error for trusted certificate entry: %v", err)
+	}
+}
@@ -0,0 +1,789 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	pfxVersion           = 3
+	encryptedDataVersion = 0
+	pkcs12SequenceTag    = 0x30
+	localKeyIDTimePrefix = "Time "
+	maxChainLength       = 64
+	// x509CertificateType is the certificate type name used by Java.
+	x509CertificateType = "X.509"
+)
+
+var (
+	dataOid                = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 1})
+	encryptedDataOid       = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 6})
+	shroudedKeyBagOid      = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 2})
+	certBagOid             = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 3})
+	x509CertificateOid     = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 22, 1})
+	friendlyNameOid        = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 20})
+	localKeyIDOid          = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 21})
+	javaTrustedKeyUsageOid = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 113894, 746875, 1, 1})
+	anyExtendedKeyUsageOid = asn1.ObjectIdentifier([]int{2, 5, 29, 37, 0})
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+// contentInfo keeps Content with its explicit [0] tag, Content.Bytes is the DER of the inner value.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+// safeBag keeps Value with its explicit [0] tag, Value.Bytes is the DER of the bag value.
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// pkcs12Cert is a certificate bag read from the PKCS#12 keystore.
+type pkcs12Cert struct {
+	alias      string
+	localKeyID []byte
+	trusted    bool
+	content    []byte
+	parsed     *x509.Certificate
+}
+
+// pkcs12Key is a private key bag read from the PKCS#12 keystore.
+type pkcs12Key struct {
+	alias      string
+	localKeyID []byte
+	encrypted  []byte
+}
+
+func explicitTag(content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+}
+
+// storePKCS12 writes keystore as PKCS#12 PFX. Private keys are kept in the plain data safe as shrouded key bags,
+// all certificates are kept in the safe encrypted with password, integrity is protected by HMAC-SHA256.
+func (ks KeyStore) storePKCS12(w io.Writer, password []byte) error {
+	var (
+		keyBags  []safeBag
+		certBags []safeBag
+		written  = make(map[string]bool)
+		keyIDs   = make(map[string]bool)
+	)
+
+	if ks.trailer != nil {
+		return fmt.Errorf("write raw trailer: %w", ErrUnsupportedEntryType)
+	}
+
+	for _, alias := range ks.storeAliases() {
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			bags, err := pkcs12PrivateKeyBags(alias, typedEntry, keyIDs, written)
+			if err != nil {
+				return fmt.Errorf("write private key entry: %w", err)
+			}
+
+			keyBags = append(keyBags, bags[0])
+			certBags = append(certBags, bags[1:]...)
+		case TrustedCertificateEntry:
+			bag, err := pkcs12TrustedCertificateBag(alias, typedEntry)
+			if err != nil {
+				return fmt.Errorf("write trusted certificate entry: %w", err)
+			}
+
+			written[string(typedEntry.Certificate.Content)] = true
+			certBags = append(certBags, bag)
+		case SecurityKeyEntry:
+			return fmt.Errorf("write security key entry: %w", ErrUnsupportedEntryType)
+		default:
+			return errors.New("got invalid entry")
+		}
+	}
+
+	var authSafe []contentInfo
+
+	if len(keyBags) > 0 {
+		keysContent, err := asn1.Marshal(keyBags)
+		if err != nil {
+			return fmt.Errorf("marshal key bags: %w", err)
+		}
+
+		ci, err := pkcs12DataContentInfo(keysContent)
+		if err != nil {
+			return fmt.Errorf("create key bags content: %w", err)
+		}
+
+		authSafe = append(authSafe, ci)
+	}
+
+	if len(certBags) > 0 {
+		certsContent, err := asn1.Marshal(certBags)
+		if err != nil {
+			return fmt.Errorf("marshal certificate bags: %w", err)
+		}
+
+		ci, err := pkcs12EncryptedContentInfo(certsContent, password)
+		if err != nil {
+			return fmt.Errorf("create certificate bags content: %w", err)
+		}
+
+		authSafe = append(authSafe, ci)
+	}
+
+	authSafeContent, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return fmt.Errorf("marshal authenticated safe: %w", err)
+	}
+
+	authSafeInfo, err := pkcs12DataContentInfo(authSafeContent)
+	if err != nil {
+		return fmt.Errorf("create authenticated safe content: %w", err)
+	}
+
+	salt := make([]byte, macSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("read random mac salt: %w", err)
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	mac, err := computeMAC(sha256Oid, authSafeContent, salt, macIterations, bmp)
+	if err != nil {
+		return fmt.Errorf("compute mac: %w", err)
+	}
+
+	pfx, err := asn1.Marshal(pfxPdu{
+		Version:  pfxVersion,
+		AuthSafe: authSafeInfo,
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: sha256Oid, Parameters: asn1.NullRawValue},
+				Digest:    mac,
+			},
+			MacSalt:    salt,
+			Iterations: macIterations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal pfx: %w", err)
+	}
+
+	if _, err := w.Write(pfx); err != nil {
+		return fmt.Errorf("write pfx: %w", err)
+	}
+
+	return nil
+}
+
+// pkcs12PrivateKeyBags returns shrouded key bag followed by bags of the certificate chain.
+// Chain certificates that were already written are skipped.
+func pkcs12PrivateKeyBags(alias string, pke PrivateKeyEntry, keyIDs, written map[string]bool) ([]safeBag, error) {
+	var keyInfo keyInfo
+	if _, err := asn1.Unmarshal(pke.encryptedPrivateKey, &keyInfo); err != nil {
+		return nil, fmt.Errorf("unmarshal encrypted key: %w", err)
+	}
+
+	if !keyInfo.Algo.Algorithm.Equal(pbes2Oid) && !keyInfo.Algo.Algorithm.Equal(pbeWithSHAAnd3KeyDESCBCOid) {
+		return nil, fmt.Errorf("private key is not encrypted for pkcs12: %w", ErrUnsupportedEntryType)
+	}
+
+	// Java parses creation time from the local key id, its uniqueness is kept by shifting the time.
+	ms := timeToMilliseconds(pke.CreationTime)
+	for keyIDs[strconv.FormatInt(ms, 10)] {
+		ms++
+	}
+
+	keyIDs[strconv.FormatInt(ms, 10)] = true
+	localKeyID := []byte(localKeyIDTimePrefix + strconv.FormatInt(ms, 10))
+
+	attributes, err := pkcs12Attributes(alias, localKeyID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	bags := []safeBag{{ID: shroudedKeyBagOid, Value: explicitTag(pke.encryptedPrivateKey), Attributes: attributes}}
+
+	for i, cert := range pke.CertificateChain {
+		if i > 0 && written[string(cert.Content)] {
+			continue
+		}
+
+		var certAttributes []pkcs12Attribute
+		if i == 0 {
+			certAttributes = attributes
+		}
+
+		bag, err := pkcs12CertBag(cert, certAttributes)
+		if err != nil {
+			return nil, fmt.Errorf("create %d certificate bag: %w", i, err)
+		}
+
+		written[string(cert.Content)] = true
+		bags = append(bags, bag)
+	}
+
+	return bags, nil
+}
+
+func pkcs12TrustedCertificateBag(alias string, tce TrustedCertificateEntry) (safeBag, error) {
+	attributes, err := pkcs12Attributes(alias, nil, true)
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	return pkcs12CertBag(tce.Certificate, attributes)
+}
+
+func pkcs12CertBag(cert Certificate, attributes []pkcs12Attribute) (safeBag, error) {
+	if !isX509Certificate(cert) {
+		return safeBag{}, fmt.Errorf("got certificate of type %s: %w", cert.Type, ErrUnsupportedEntryType)
+	}
+
+	value, err := asn1.Marshal(certBag{ID: x509CertificateOid, Data: cert.Content})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("marshal certificate bag: %w", err)
+	}
+
+	return safeBag{ID: certBagOid, Value: explicitTag(value), Attributes: attributes}, nil
+}
+
+func pkcs12Attributes(alias string, localKeyID []byte, trusted bool) ([]pkcs12Attribute, error) {
+	runes := utf16.Encode([]rune(alias))
+	name := make([]byte, 0, len(runes)*2)
+
+	for _, r := range runes {
+		name = append(name, byte(r>>8), byte(r))
+	}
+
+	friendlyName, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: name})
+	if err != nil {
+		return nil, fmt.Errorf("marshal friendly name: %w", err)
+	}
+
+	attributes := []pkcs12Attribute{{ID: friendlyNameOid, Value: setOf(friendlyName)}}
+
+	if localKeyID != nil {
+		keyID, err := asn1.Marshal(localKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("marshal local key id: %w", err)
+		}
+
+		attributes = append(attributes, pkcs12Attribute{ID: localKeyIDOid, Value: setOf(keyID)})
+	}
+
+	if trusted {
+		usage, err := asn1.Marshal(anyExtendedKeyUsageOid)
+		if err != nil {
+			return nil, fmt.Errorf("marshal trusted key usage: %w", err)
+		}
+
+		attributes = append(attributes, pkcs12Attribute{ID: javaTrustedKeyUsageOid, Value: setOf(usage)})
+	}
+
+	return attributes, nil
+}
+
+func isX509Certificate(cert Certificate) bool {
+	return cert.Type == x509CertificateType || cert.Type == defaultCertificateType
+}
+
+func setOf(content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: content}
+}
+
+func pkcs12DataContentInfo(content []byte) (contentInfo, error) {
+	data, err := asn1.Marshal(content)
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("marshal data: %w", err)
+	}
+
+	return contentInfo{ContentType: dataOid, Content: explicitTag(data)}, nil
+}
+
+func pkcs12EncryptedContentInfo(content []byte, password []byte) (contentInfo, error) {
+	algo, encrypted, err := encryptPBES2(rand.Reader, content, password)
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("encrypt content: %w", err)
+	}
+
+	data, err := asn1.Marshal(encryptedData{
+		Version: encryptedDataVersion,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                dataOid,
+			ContentEncryptionAlgorithm: algo,
+			EncryptedContent:           encrypted,
+		},
+	})
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("marshal encrypted data: %w", err)
+	}
+
+	return contentInfo{ContentType: encryptedDataOid, Content: explicitTag(data)}, nil
+}
+
+// loadPKCS12 reads PKCS#12 PFX from r, checks its MAC and fills keystore with entries.
+func (ks KeyStore) loadPKCS12(r io.Reader, password []byte, opts loadOptions) error {
+	pfx, authSafeContent, err := readPKCS12(r)
+	if err != nil {
+		return err
+	}
+
+	if !opts.skipIntegrityCheck {
+		if err := checkIterationLimit("mac", pfx.MacData.Iterations, opts.maxIterations); err != nil {
+			return err
+		}
+
+		verified, err := verifyPKCS12(pfx, authSafeContent, password)
+		if err != nil {
+			return err
+		}
+
+		if !verified {
+			return ErrInvalidDigest
+		}
+	}
+
+	var authSafe []contentInfo
+	if err := unmarshalStrict(authSafeContent, &authSafe); err != nil {
+		return pkcs12CorruptHeader(fmt.Errorf("unmarshal authenticated safe: %w", err))
+	}
+
+	bags := pkcs12Bags{opts: opts}
+
+	for i, ci := range authSafe {
+		if err := readPKCS12SafeContents(ci, password, &bags); err != nil {
+			return fmt.Errorf("read %d safe contents: %w", i, err)
+		}
+	}
+
+	keys, certs := bags.keys, bags.certs
+
+	loaded := make(map[string]string, len(keys)+len(certs))
+	inChains := make(map[string]bool, len(certs))
+
+	for _, cert := range certs {
+		if !cert.trusted {
+			continue
+		}
+
+		if err := ks.setLoadedEntry(loaded, cert.alias, TrustedCertificateEntry{
+			CreationTime: creationTimeFromLocalKeyID(cert.localKeyID),
+			Certificate:  Certificate{Type: x509CertificateType, Content: cert.content},
+		}, opts); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		chain := pkcs12CertificateChain(key.localKeyID, certs)
+		for _, cert := range chain {
+			inChains[string(cert.Content)] = true
+		}
+
+		if err := ks.setLoadedEntry(loaded, key.alias, PrivateKeyEntry{
+			encryptedPrivateKey: key.encrypted,
+			CreationTime:        creationTimeFromLocalKeyID(key.localKeyID),
+			CertificateChain:    chain,
+		}, opts); err != nil {
+			return err
+		}
+	}
+
+	for _, cert := range certs {
+		if !cert.trusted && !inChains[string(cert.content)] {
+			bags.skip(cert.alias,
+				fmt.Errorf("got certificate neither trusted nor in a chain: %w", ErrUnsupportedEntryType))
+		}
+	}
+
+	return nil
+}
+
+// readPKCS12 decodes pfx and returns it with the content of authenticated safe.
+func readPKCS12(r io.Reader) (pfxPdu, []byte, error) {
+	encoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return pfxPdu{}, nil, fmt.Errorf("read pfx: %w", err)
+	}
+
+	var pfx pfxPdu
+	if err := unmarshalStrict(encoded, &pfx); err != nil {
+		return pfxPdu{}, nil, pkcs12CorruptHeader(fmt.Errorf("unmarshal pfx: %w", err))
+	}
+
+	if pfx.Version != pfxVersion {
+		return pfxPdu{}, nil, pkcs12CorruptHeader(fmt.Errorf("got version %d: %w", pfx.Version, ErrUnknownVersion))
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(dataOid) {
+		return pfxPdu{}, nil, fmt.Errorf("authenticated safe content type: %w",
+			ErrUnsupportedAlgorithm{OID: pfx.AuthSafe.ContentType})
+	}
+
+	var authSafeContent []byte
+	if err := unmarshalStrict(pfx.AuthSafe.Content.Bytes, &authSafeContent); err != nil {
+		return pfxPdu{}, nil, pkcs12CorruptHeader(fmt.Errorf("unmarshal authenticated safe content: %w", err))
+	}
+
+	return pfx, authSafeContent, nil
+}
+
+// pkcs12CorruptHeader reports malformed PFX or authenticated safe with ErrCorrupt.
+func pkcs12CorruptHeader(err error) error {
+	return ErrCorrupt{Offset: 0, Entry: -1, Err: asn1Truncated(err)}
+}
+
+// asn1Truncated reports truncated DER data with io.ErrUnexpectedEOF.
+func asn1Truncated(err error) error {
+	var syntaxErr asn1.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Msg == "data truncated" {
+		return fmt.Errorf("%v: %w", err, io.ErrUnexpectedEOF)
+	}
+
+	return err
+}
+
+// verifyPKCS12 checks mac of authenticated safe content.
+func verifyPKCS12(pfx pfxPdu, authSafeContent []byte, password []byte) (bool, error) {
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) == 0 {
+		return false, errors.New("got keystore without mac")
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	mac, err := computeMAC(pfx.MacData.Mac.Algorithm.Algorithm, authSafeContent,
+		pfx.MacData.MacSalt, pfx.MacData.Iterations, bmp)
+	if err != nil {
+		return false, fmt.Errorf("compute mac: %w", err)
+	}
+
+	if hmac.Equal(mac, pfx.MacData.Mac.Digest) {
+		return true, nil
+	}
+
+	// keystores with non-ASCII passwords written by older versions use the legacy password encoding
+	legacyBytes := legacyPasswordBytes(password)
+	if legacyBytes == nil {
+		return false, nil
+	}
+
+	legacyBMP := append(legacyBytes, 0, 0)
+	defer zeroing(legacyBMP)
+
+	mac, err = computeMAC(pfx.MacData.Mac.Algorithm.Algorithm, authSafeContent,
+		pfx.MacData.MacSalt, pfx.MacData.Iterations, legacyBMP)
+	if err != nil {
+		return false, fmt.Errorf("compute mac: %w", err)
+	}
+
+	return hmac.Equal(mac, pfx.MacData.Mac.Digest), nil
+}
+
+// readPKCS12SafeContents decrypts safe contents if needed and adds every safe bag to bags as it is decoded.
+func readPKCS12SafeContents(ci contentInfo, password []byte, bags *pkcs12Bags) error {
+	var content []byte
+
+	switch {
+	case ci.ContentType.Equal(dataOid):
+		if err := unmarshalStrict(ci.Content.Bytes, &content); err != nil {
+			return bags.corrupt("", fmt.Errorf("unmarshal data: %w", err))
+		}
+	case ci.ContentType.Equal(encryptedDataOid):
+		var ed encryptedData
+		if err := unmarshalStrict(ci.Content.Bytes, &ed); err != nil {
+			return bags.corrupt("", fmt.Errorf("unmarshal encrypted data: %w", err))
+		}
+
+		iterations := pbeIterations(ed.EncryptedContentInfo.ContentEncryptionAlgorithm)
+		if err := checkIterationLimit("encrypted data", iterations, bags.opts.maxIterations); err != nil {
+			return err
+		}
+
+		decrypted, err := decryptPBE(ed.EncryptedContentInfo.ContentEncryptionAlgorithm,
+			ed.EncryptedContentInfo.EncryptedContent, password)
+		if err != nil {
+			return fmt.Errorf("decrypt encrypted data: %w", err)
+		}
+
+		content = decrypted
+	default:
+		return fmt.Errorf("safe contents type: %w", ErrUnsupportedAlgorithm{OID: ci.ContentType})
+	}
+
+	var safeContents asn1.RawValue
+	if err := unmarshalStrict(content, &safeContents); err != nil {
+		return bags.corrupt("", fmt.Errorf("unmarshal safe bags: %w", err))
+	}
+
+	if safeContents.Class != asn1.ClassUniversal || safeContents.Tag != asn1.TagSequence {
+		return bags.corrupt("", errors.New("got safe bags which are not a sequence"))
+	}
+
+	for i, rest := 0, safeContents.Bytes; len(rest) > 0; i++ {
+		var (
+			bag safeBag
+			err error
+		)
+
+		if rest, err = asn1.Unmarshal(rest, &bag); err != nil {
+			return bags.corrupt("", fmt.Errorf("unmarshal %d safe bag: %w", i, err))
+		}
+
+		if err := bags.add(bag); err != nil {
+			return fmt.Errorf("read %d safe bag: %w", i, err)
+		}
+
+		bags.bagNum++
+	}
+
+	return nil
+}
+
+// pkcs12Bags collects keys and certificates of the PKCS#12 keystore, the load limits are checked
+// for every bag before it is parsed.
+type pkcs12Bags struct {
+	opts     loadOptions
+	keys     []pkcs12Key
+	certs    []pkcs12Cert
+	entryNum int
+	// bagNum is the number of bags read from all safe contents, it is the index of the bag being read.
+	bagNum int
+}
+
+// corrupt reports malformed bag being read with ErrCorrupt, the offset of bags is unknown as they may be encrypted.
+func (b *pkcs12Bags) corrupt(alias string, err error) error {
+	return ErrCorrupt{Offset: -1, Entry: b.bagNum, Alias: alias, Err: asn1Truncated(err)}
+}
+
+// addEntry counts keystore entries, which are private keys and trusted certificates.
+func (b *pkcs12Bags) addEntry() error {
+	b.entryNum++
+
+	return checkLimit("number of entries", uint64(b.entryNum), int64(b.opts.maxEntries))
+}
+
+func (b *pkcs12Bags) add(bag safeBag) error {
+	var (
+		alias      string
+		localKeyID []byte
+		trusted    bool
+	)
+
+	for _, attr := range bag.Attributes {
+		switch {
+		case attr.ID.Equal(friendlyNameOid):
+			var name asn1.RawValue
+			if err := unmarshalStrict(attr.Value.Bytes, &name); err != nil {
+				return b.corrupt("", fmt.Errorf("unmarshal friendly name: %w", err))
+			}
+
+			decoded, err := decodeBMPString(name.Bytes)
+			if err != nil {
+				return b.corrupt("", fmt.Errorf("decode friendly name: %w", err))
+			}
+
+			alias = decoded
+		case attr.ID.Equal(localKeyIDOid):
+			if err := unmarshalStrict(attr.Value.Bytes, &localKeyID); err != nil {
+				return b.corrupt(alias, fmt.Errorf("unmarshal local key id: %w", err))
+			}
+		case attr.ID.Equal(javaTrustedKeyUsageOid):
+			trusted = true
+		}
+	}
+
+	if alias == "" {
+		alias = hex.EncodeToString(localKeyID)
+	}
+
+	switch {
+	case bag.ID.Equal(shroudedKeyBagOid):
+		if err := checkLimit("private key size", uint64(len(bag.Value.Bytes)), int64(b.opts.maxKeySize)); err != nil {
+			return err
+		}
+
+		if err := checkIterationLimit("private key", keyIterations(bag.Value.Bytes), b.opts.maxIterations); err != nil {
+			return err
+		}
+
+		if err := b.addEntry(); err != nil {
+			return err
+		}
+
+		b.keys = append(b.keys, pkcs12Key{alias: alias, localKeyID: localKeyID, encrypted: bag.Value.Bytes})
+	case bag.ID.Equal(certBagOid):
+		var cb certBag
+		if err := unmarshalStrict(bag.Value.Bytes, &cb); err != nil {
+			return b.corrupt(alias, fmt.Errorf("unmarshal certificate bag: %w", err))
+		}
+
+		if !cb.ID.Equal(x509CertificateOid) {
+			b.skip(alias, fmt.Errorf("got certificate type %v: %w", cb.ID, ErrUnsupportedCertificateType))
+
+			return nil
+		}
+
+		if err := checkLimit("certificate size", uint64(len(cb.Data)), int64(b.opts.maxCertificateSize)); err != nil {
+			return err
+		}
+
+		if trusted {
+			if err := b.addEntry(); err != nil {
+				return err
+			}
+		}
+
+		parsed, err := x509.ParseCertificate(cb.Data)
+		if err != nil {
+			return b.corrupt(alias, fmt.Errorf("parse certificate: %w", err))
+		}
+
+		b.certs = append(b.certs, pkcs12Cert{
+			alias:      alias,
+			localKeyID: localKeyID,
+			trusted:    trusted,
+			content:    cb.Data,
+			parsed:     parsed,
+		})
+	default:
+		b.skip(alias, fmt.Errorf("bag type: %w", ErrUnsupportedAlgorithm{OID: bag.ID}))
+	}
+
+	return nil
+}
+
+// skip reports the bag which is not loaded to the function set by WithOnSkippedEntry.
+func (b *pkcs12Bags) skip(alias string, err error) {
+	if b.opts.onSkippedEntry != nil {
+		b.opts.onSkippedEntry(alias, err)
+	}
+}
+
+// pkcs12CertificateChain finds certificate by local key id and follows its issuers.
+func pkcs12CertificateChain(localKeyID []byte, certs []pkcs12Cert) []Certificate {
+	var current *pkcs12Cert
+
+	for i := range certs {
+		if len(localKeyID) > 0 && bytes.Equal(certs[i].localKeyID, localKeyID) {
+			current = &certs[i]
+
+			break
+		}
+	}
+
+	var chain []Certificate
+
+	for current != nil && len(chain) < maxChainLength {
+		chain = append(chain, Certificate{Type: x509CertificateType, Content: current.content})
+
+		if bytes.Equal(current.parsed.RawIssuer, current.parsed.RawSubject) {
+			break
+		}
+
+		var issuer *pkcs12Cert
+
+		for i := range certs {
+			if bytes.Equal(certs[i].parsed.RawSubject, current.parsed.RawIssuer) {
+				issuer = &certs[i]
+
+				break
+			}
+		}
+
+		current = issuer
+	}
+
+	return chain
+}
+
+func creationTimeFromLocalKeyID(localKeyID []byte) (t time.Time) {
+	id := string(localKeyID)
+	if !strings.HasPrefix(id, localKeyIDTimePrefix) {
+		return
+	}
+
+	ms, err := strconv.ParseInt(strings.TrimPrefix(id, localKeyIDTimePrefix), 10, 64)
+	if err != nil {
+		return
+	}
+
+	return millisecondsToTime(ms)
+}
+
+func decodeBMPString(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", errors.New("got odd length of bmp string")
+	}
+
+	s := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		s = append(s, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+
+	return string(utf16.Decode(s)), nil
+}
+
+func unmarshalStrict(b []byte, val interface{}) error {
+	rest, err := asn1.Unmarshal(b, val)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 0 {
+		return errors.New("got trailing data")
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadPKCS12(t *testing.T) {
+	t.Parallel()
+
+	password := []byte{'p', 'a', 's', 's', 'w', 'o', 'r', 'd'}
+	defer zeroing(password)
+
+	expectedPK := readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY")
+	expectedCert := readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")
+
+	for _, name := range []string{"./testdata/keystore.p12", "./testdata/keystore_legacy.p12"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("open test data keystore file: %s", err)
+		}
+
+		keyStore := New()
+
+		if err := keyStore.Load(f, password); err != nil {
+			t.Fatalf("decode test data keystore %s: %s", name, err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("close test data keystore file: %s", err)
+		}
+
+		if keyStore.storeType != PKCS12StoreType {
+			t.Errorf("unexpected store type of %s: %d", name, keyStore.storeType)
+		}
+
+		actualPKE, err := keyStore.GetPrivateKeyEntry("alias", password)
+		if err != nil {
+			t.Fatalf("get private key entry from %s: %s", name, err)
+		}
+
+		if !reflect.DeepEqual(actualPKE.PrivateKey, expectedPK) {
+			t.Errorf("unexpected private key in %s", name)
+		}
+
+		expectedChain := []Certificate{{Type: "X.509", Content: expectedCert}}
+		if !reflect.DeepEqual(actualPKE.CertificateChain, expectedChain) {
+			t.Errorf("unexpected certificate chain in %s", name)
+		}
+	}
+}
+
+func TestLoadKeytoolPKCS12(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(readKeytoolKeyStore(t, "keytool.p12")), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if ks.StoreType() != PKCS12StoreType {
+		t.Errorf("unexpected store type %d", ks.StoreType())
+	}
+
+	// TLSCertificate checks that the private key matches the certificate.
+	if _, err := ks.TLSCertificate("key", password); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tce, err := ks.GetTrustedCertificateEntry("ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chain) != 1 || !reflect.DeepEqual(tce.Certificate, chain[0]) {
+		t.Errorf("unexpected certificate chain %+v", chain)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.Load(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loaded.TLSCertificate("key", password); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreLoadPKCS12(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	keyPassword := []byte("keypassword")
+
+	pke := PrivateKeyEntry{
+		CreationTime: time.Unix(1600000000, 0),
+		PrivateKey:   readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X.509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+		},
+	}
+	tce := TrustedCertificateEntry{
+		Certificate: Certificate{Type: "X.509", Content: readCertificate(t)},
+	}
+
+	ks := New(WithStoreType(PKCS12StoreType))
+
+	if err := ks.SetPrivateKeyEntry("pke-alias", pke, keyPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetTrustedCertificateEntry("tce-alias", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	actualPKE, err := loaded.GetPrivateKeyEntry("pke-alias", keyPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actualPKE, pke) {
+		t.Errorf("private key entries not equal")
+	}
+
+	actualTCE, err := loaded.GetTrustedCertificateEntry("tce-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actualTCE.Certificate, tce.Certificate) {
+		t.Errorf("trusted certificate entries not equal")
+	}
+
+	wrong := New()
+	if err := wrong.Load(bytes.NewReader(buf.Bytes()), keyPassword); err == nil {
+		t.Error("load with wrong password must fail")
+	}
+}
+
+func readPEMBlock(t testing.TB, name, blockType string) []byte {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := pem.Decode(data)
+	if b == nil {
+		t.Fatal("should have at least one pem block")
+	}
+
+	if b.Type != blockType {
+		t.Fatalf("should be a %s", blockType)
+	}
+
+	return b.Bytes
+}
+
+func TestLoadPKCS12SkippedBags(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	cert := Certificate{Type: "X.509", Content: readCertificate(t)}
+
+	trusted, err := pkcs12TrustedCertificateBag("ca", TrustedCertificateEntry{Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := func(alias string) []pkcs12Attribute {
+		a, err := pkcs12Attributes(alias, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return a
+	}
+
+	unmatched, err := pkcs12CertBag(cert, attributes("unmatched"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyBagOid := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	secretBagOid := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 5}
+	null, _ := asn1.Marshal(asn1.NullRawValue)
+
+	data := marshalTestPKCS12(t, password, []safeBag{
+		{ID: keyBagOid, Value: explicitTag(null), Attributes: attributes("key")},
+		trusted,
+		{ID: secretBagOid, Value: explicitTag(null), Attributes: attributes("secret")},
+		unmatched,
+	})
+
+	skipped := make(map[string]error)
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(data), password, WithOnSkippedEntry(func(alias string, err error) {
+		skipped[alias] = err
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ks.Aliases(), []string{"ca"}) {
+		t.Errorf("unexpected aliases %v", ks.Aliases())
+	}
+
+	var unsupported ErrUnsupportedAlgorithm
+
+	if len(skipped) != 3 || !errors.As(skipped["key"], &unsupported) || !unsupported.OID.Equal(keyBagOid) ||
+		!errors.As(skipped["secret"], &unsupported) || !errors.Is(skipped["unmatched"], ErrUnsupportedEntryType) {
+		t.Errorf("unexpected skipped bags %v", skipped)
+	}
+}
+
+// marshalTestPKCS12 returns PKCS12 keystore with the bags in the plain data safe and HMAC-SHA256 integrity.
+func marshalTestPKCS12(t *testing.T, password []byte, bags []safeBag) []byte {
+	t.Helper()
+
+	content, err := asn1.Marshal(bags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ci, err := pkcs12DataContentInfo(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authSafeContent, err := asn1.Marshal([]contentInfo{ci})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authSafe, err := pkcs12DataContentInfo(authSafeContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	salt := make([]byte, macSaltLen)
+
+	mac, err := computeMAC(sha256Oid, authSafeContent, salt, 1, bmpPassword(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pfx, err := asn1.Marshal(pfxPdu{
+		Version:  pfxVersion,
+		AuthSafe: authSafe,
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: sha256Oid, Parameters: asn1.NullRawValue},
+				Digest:    mac,
+			},
+			MacSalt:    salt,
+			Iterations: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pfx
+}
+
+func TestLoadCorruptPKCS12(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var corrupt ErrCorrupt
+
+	ks := New()
+
+	err = ks.Load(bytes.NewReader(data[:len(data)-100]), []byte("password"))
+	if !errors.As(err, &corrupt) || !errors.Is(err, io.ErrUnexpectedEOF) || corrupt.Entry != -1 {
+		t.Errorf("unexpected error for truncated keystore: %v", err)
+	}
+
+	if _, err := Verify(bytes.NewReader(data[:len(data)-100]), []byte("password")); !errors.As(err, &corrupt) {
+		t.Errorf("unexpected error for verified truncated keystore: %v", err)
+	}
+
+	// the bag of unsupported type is skipped, the following one is cut inside its value
+	validBag, err := asn1.Marshal(safeBag{ID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: explicitTag([]byte{5, 0})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	safeContents, err := asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      append(append([]byte(nil), validBag...), validBag[:len(validBag)-1]...),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := asn1.Marshal(safeContents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bags := pkcs12Bags{bagNum: 1}
+
+	err = readPKCS12SafeContents(contentInfo{ContentType: dataOid, Content: explicitTag(content)}, nil, &bags)
+	if !errors.As(err, &corrupt) || corrupt.Entry != 2 || corrupt.Offset != -1 {
+		t.Errorf("unexpected error for truncated bag: %v", err)
+	}
+}
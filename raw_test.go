@@ -0,0 +1,158 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRawTrailer(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	creationTime := time.Unix(1600000000, 0)
+	tce := TrustedCertificateEntry{
+		CreationTime: creationTime,
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}
+
+	var buf bytes.Buffer
+
+	kse := keyStoreEncoder{w: &buf, md: sha1.New()}
+	_, _ = kse.md.Write(passwordBytes(password))
+	_, _ = kse.md.Write(whitenerMessage)
+
+	for _, err := range []error{
+		kse.writeUint32(jksmagic),
+		kse.writeUint32(version02),
+		kse.writeUint32(3),
+		kse.writeTrustedCertificateEntry("a", tce),
+		kse.writeUint32(9),
+		kse.writeString("x"),
+		kse.writeUint64(uint64(timeToMilliseconds(creationTime))),
+		kse.writeBytes([]byte("opaque")),
+		kse.writeTrustedCertificateEntry("z", tce),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := kse.writeBytes(kse.md.Sum(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New(WithOrderedAliases())
+	if err := ks.Load(bytes.NewReader(buf.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ks.Aliases(), []string{"a"}) {
+		t.Fatalf("unexpected aliases %v", ks.Aliases())
+	}
+
+	trailer, ok := ks.RawTrailer()
+	if !ok {
+		t.Fatal("raw trailer not found")
+	}
+
+	if trailer.Tag != 9 || trailer.Alias != "x" || trailer.EntryNum != 2 || !trailer.CreationTime.Equal(creationTime) ||
+		!bytes.HasPrefix(trailer.Payload, []byte("opaque")) {
+		t.Errorf("unexpected raw trailer %+v", trailer)
+	}
+
+	payloadLen := len(trailer.Payload)
+	for _, tt := range []struct {
+		option LoadOption
+		err    error
+	}{
+		{WithMaxTrailerSize(payloadLen), nil},
+		{WithMaxTrailerSize(payloadLen - 1), ErrLimitExceeded},
+		{WithMaxSize(int64(buf.Len())), nil},
+		// the limit is reached inside the trailer payload, before the digest
+		{WithMaxSize(int64(buf.Len() - sha1.Size - 1)), ErrLimitExceeded},
+	} {
+		limited := New()
+		if err := limited.Load(bytes.NewReader(buf.Bytes()), password, tt.option); !errors.Is(err, tt.err) {
+			t.Errorf("unexpected error for trailer of %d bytes and keystore of %d bytes: %v", payloadLen, buf.Len(), err)
+		}
+	}
+
+	var stored bytes.Buffer
+	if err := ks.Store(&stored, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stored.Bytes(), buf.Bytes()) {
+		t.Error("stored keystore differs from the loaded one")
+	}
+
+	// "z" is hidden in the trailer, Java would read it instead of the new entry
+	for _, alias := range []string{"x", "z", "Z"} {
+		if err := ks.SetTrustedCertificateEntry(alias, tce); !errors.Is(err, ErrAliasExists) {
+			t.Errorf("unexpected error setting alias %q of raw trailer: %v", alias, err)
+		}
+	}
+
+	if err := ks.SetTrustedCertificateEntry("b", tce); err != nil {
+		t.Fatal(err)
+	}
+
+	stored.Reset()
+
+	if err := ks.Store(&stored, password); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDecoder(bytes.NewReader(stored.Bytes()), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Len() != 4 {
+		t.Errorf("unexpected number of entries %d", d.Len())
+	}
+
+	var decoded []string
+
+	for {
+		alias, entry, err := d.Next()
+		if err == io.EOF { // nolint: errorlint
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded = append(decoded, fmt.Sprintf("%s %T", alias, entry))
+	}
+
+	if !reflect.DeepEqual(decoded, []string{
+		"a keystore.TrustedCertificateEntry", "b keystore.TrustedCertificateEntry", "x keystore.RawTrailer",
+	}) {
+		t.Errorf("unexpected decoded entries %v", decoded)
+	}
+
+	loaded := New(WithOrderedAliases())
+	if err := loaded.Load(bytes.NewReader(stored.Bytes()), password); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.Aliases(), []string{"a", "b"}) || !loaded.IsTrustedCertificateEntry("b") {
+		t.Errorf("unexpected aliases after edit %v", loaded.Aliases())
+	}
+
+	if loadedTrailer, ok := loaded.RawTrailer(); !ok || !reflect.DeepEqual(loadedTrailer, trailer) {
+		t.Errorf("unexpected raw trailer after edit %+v", loadedTrailer)
+	}
+
+	if _, err := Convert(loaded, PKCS12StoreType, nil); !errors.Is(err, ErrUnsupportedEntryType) {
+		t.Errorf("unexpected error converting raw trailer to pkcs12: %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package keystore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Decoder reads entries of JKS or JCEKS keystore one at a time without keeping the whole keystore in memory.
+// Entries are PrivateKeyEntry, TrustedCertificateEntry, SecurityKeyEntry with encrypted keys
+// and RawTrailer, use their Decrypt methods to get the keys.
+type Decoder struct {
+	signReader *Reader
+	ksd        *keyStoreDecoder
+	storeType  int
+	version    uint32
+	entryNum   uint32
+	read       uint32
+	opts       loadOptions
+	err        error
+}
+
+// NewDecoder reads the header of the keystore from r and returns Decoder of its entries.
+// The keystore signature is verified with the password after the last entry unless WithoutIntegrityCheck is used.
+// PKCS12 keystores are reported with ErrUnsupportedFormat, use KeyStore.Load to read them.
+// It is strongly recommended to fill password slice with zero after usage.
+func NewDecoder(r io.Reader, password []byte, options ...LoadOption) (*Decoder, error) {
+	opts := newLoadOptions(options)
+	br := bufio.NewReader(newLimitedReader(r, opts.maxSize))
+
+	magic, err := peekMagic(br)
+	if err != nil {
+		return nil, err
+	}
+
+	storeType, err := detectStoreType(magic)
+	if err != nil {
+		return nil, err
+	}
+
+	if storeType == PKCS12StoreType {
+		return nil, fmt.Errorf("got pkcs12 keystore: %w", ErrUnsupportedFormat)
+	}
+
+	return newDecoder(br, storeType, password, opts)
+}
+
+func newDecoder(r io.Reader, storeType int, password []byte, opts loadOptions) (*Decoder, error) {
+	signReader, err := newVerifyingReader(r, password)
+	if err != nil {
+		return nil, err
+	}
+
+	ksd := newKeyStoreDecoder(signReader, signReader.md)
+	ksd.opts = opts
+
+	corrupt := func(err error) error {
+		if errors.Is(err, ErrLimitExceeded) {
+			return err
+		}
+
+		return ErrCorrupt{Offset: 0, Entry: -1, Err: unexpectedEOF(err)}
+	}
+
+	if _, err := ksd.readUint32(); err != nil {
+		return nil, corrupt(fmt.Errorf("read keystore type jks or jceks magic: %w", err))
+	}
+
+	version, err := ksd.readUint32()
+	if err != nil {
+		return nil, corrupt(fmt.Errorf("read version: %w", err))
+	}
+
+	if version != version01 && version != version02 {
+		return nil, corrupt(fmt.Errorf("got version %d: %w", version, ErrUnknownVersion))
+	}
+
+	entryNum, err := ksd.readUint32()
+	if err != nil {
+		return nil, corrupt(fmt.Errorf("read number of entries: %w", err))
+	}
+
+	if err := checkLimit("number of entries", uint64(entryNum), int64(opts.maxEntries)); err != nil {
+		return nil, err
+	}
+
+	return &Decoder{
+		signReader: signReader,
+		ksd:        ksd,
+		storeType:  storeType,
+		version:    version,
+		entryNum:   entryNum,
+		opts:       opts,
+	}, nil
+}
+
+// StoreType returns type of the keystore read by the Decoder.
+func (d *Decoder) StoreType() int {
+	return d.storeType
+}
+
+// Len returns number of entries declared in the keystore header.
+func (d *Decoder) Len() int {
+	return int(d.entryNum)
+}
+
+// Next returns alias and entry of the next keystore entry.
+// After the last entry it verifies the keystore signature and returns io.EOF itself, not wrapped,
+// if it is valid or the integrity check is skipped. A keystore ending inside an entry is reported
+// with io.ErrUnexpectedEOF.
+// Aliases are returned as they are stored in the keystore.
+// An entry with unknown tag is returned as RawTrailer which includes all the following entries, so it is the last one.
+func (d *Decoder) Next() (string, interface{}, error) {
+	if d.err != nil {
+		return "", nil, d.err
+	}
+
+	if d.read < d.entryNum {
+		offset := d.ksd.offset()
+
+		alias, entry, err := d.ksd.readEntry(d.version, d.entryNum-d.read)
+		if errors.Is(err, ErrLimitExceeded) {
+			d.err = fmt.Errorf("read %d entry %q: %w", d.read, alias, err)
+
+			return "", nil, d.err
+		}
+
+		if err != nil {
+			d.err = ErrCorrupt{
+				Offset: offset,
+				Entry:  int(d.read),
+				Alias:  alias,
+				Err:    fmt.Errorf("read %d entry: %w", d.read, unexpectedEOF(err)),
+			}
+
+			return "", nil, d.err
+		}
+
+		if trailer, ok := entry.(RawTrailer); ok {
+			d.read += trailer.EntryNum
+		} else {
+			d.read++
+		}
+
+		return alias, entry, nil
+	}
+
+	d.err = d.verify()
+
+	return "", nil, d.err
+}
+
+// peekMagic returns the magic of the keystore without reading it, a keystore shorter than the magic
+// is reported with ErrCorrupt.
+func peekMagic(br *bufio.Reader) ([]byte, error) {
+	magic, err := br.Peek(magicLen)
+	if errors.Is(err, io.EOF) {
+		return nil, ErrCorrupt{Offset: 0, Entry: -1, Err: fmt.Errorf("read keystore magic: %w", unexpectedEOF(err))}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read keystore magic: %w", err)
+	}
+
+	return magic, nil
+}
+
+// unexpectedEOF reports io.EOF as io.ErrUnexpectedEOF, as the keystore can't end inside the header or an entry.
+// Otherwise the truncated keystore would be taken for the end of the entries.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%v: %w", err, io.ErrUnexpectedEOF)
+	}
+
+	return err
+}
+
+// checkIterationLimit returns ErrLimitExceeded if positive iteration count is greater than positive limit.
+// Invalid iteration counts are reported when the key is derived.
+func checkIterationLimit(name string, iterations, limit int) error {
+	if iterations < 1 {
+		return nil
+	}
+
+	return checkLimit(name+" iteration count", uint64(iterations), int64(limit))
+}
+
+// checkLimit returns ErrLimitExceeded if value is greater than positive limit.
+func checkLimit(name string, value uint64, limit int64) error {
+	if limit > 0 && value > uint64(limit) {
+		return fmt.Errorf("got %s %d, limit is %d: %w", name, value, limit, ErrLimitExceeded)
+	}
+
+	return nil
+}
+
+// limitedReader returns ErrLimitExceeded instead of io.EOF as io.LimitedReader does.
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func newLimitedReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+
+	return &limitedReader{r: r, n: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if l.n <= 0 {
+		// Allow to read EOF of the stream which exactly fits the limit.
+		n, err := l.r.Read(make([]byte, 1))
+		if n > 0 {
+			l.err = fmt.Errorf("got keystore larger than limit: %w", ErrLimitExceeded)
+
+			return 0, l.err
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+
+	return n, err
+}
+
+// verify reads the rest of the keystore, the digest reader hides the trailing signature.
+func (d *Decoder) verify() error {
+	if d.opts.skipIntegrityCheck {
+		return io.EOF
+	}
+
+	if _, err := io.Copy(ioutil.Discard, d.ksd.r); err != nil {
+		return fmt.Errorf("read digest: %w", err)
+	}
+
+	verified, err := d.signReader.VerifySign()
+	if err != nil {
+		return fmt.Errorf("read digest: %w", err)
+	}
+
+	if !verified {
+		return ErrInvalidDigest
+	}
+
+	return io.EOF
+}
@@ -0,0 +1,170 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	ks := New(WithStoreType(JCEKSStoreType), WithOrderedAliases())
+
+	if err := ks.SetPrivateKeyEntry("pke-alias", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetTrustedCertificateEntry("tce-alias", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetSecurityKeyEntry("ske-alias", SecurityKeyEntry{
+		CreationTime: time.Now(),
+		SecurityKey:  []byte("0123456789abcdef"),
+		Algorithm:    "AES",
+	}, password); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDecoder(bytes.NewReader(buf.Bytes()), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.StoreType() != JCEKSStoreType || d.Len() != 3 {
+		t.Errorf("unexpected store type %d or number of entries %d", d.StoreType(), d.Len())
+	}
+
+	decoded := make(map[string]interface{})
+
+	for {
+		alias, entry, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded[alias] = entry
+	}
+
+	if _, _, err := d.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("unexpected error after the last entry: %v", err)
+	}
+
+	for alias, entry := range ks.m {
+		if reflect.TypeOf(decoded[alias]) != reflect.TypeOf(entry) {
+			t.Errorf("unexpected entry %q of type %T", alias, decoded[alias])
+		}
+	}
+
+	pke, err := decoded["pke-alias"].(PrivateKeyEntry).Decrypt(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(pke.PrivateKey, readPrivateKey(t)) {
+		t.Error("decrypted private key is not equal to the original one")
+	}
+
+	ske, err := decoded["ske-alias"].(SecurityKeyEntry).Decrypt(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(ske.SecurityKey, []byte("0123456789abcdef")) {
+		t.Error("decrypted security key is not equal to the original one")
+	}
+}
+
+func TestDecoderInvalidDigest(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDecoder(bytes.NewReader(data), []byte("wrongpassword"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alias, _, err := d.Next()
+	if err != nil || alias != "alias" {
+		t.Fatalf("unexpected first entry %q: %v", alias, err)
+	}
+
+	if _, _, err := d.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("should fail with invalid digest, got %v", err)
+	}
+
+	p12, err := ioutil.ReadFile("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDecoder(bytes.NewReader(p12), []byte("password")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("unexpected error for pkcs12 keystore: %v", err)
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+	load := func(data []byte) error {
+		ks := New()
+
+		return ks.Load(bytes.NewReader(data), password)
+	}
+
+	data, err := ioutil.ReadFile("./testdata/keystore.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every prefix of the keystore lacks a part of an entry or the digest
+	for n := magicLen; n < len(data); n++ {
+		err := load(data[:n])
+		if err == nil || errors.Is(err, io.EOF) {
+			t.Fatalf("unexpected error for keystore truncated to %d bytes: %v", n, err)
+		}
+	}
+
+	header := []byte{0xfe, 0xed, 0xfe, 0xed, 0, 0, 0, 2, 0, 0, 0, 1}
+	junk := bytes.Repeat([]byte{0xff}, 20)
+
+	// the junk is taken for the digest, so the declared entry is missing
+	err = load(append(header, junk...))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		t.Errorf("unexpected error for keystore without entries: %v", err)
+	}
+
+	forged := append([]byte{0xfe, 0xed, 0xfe, 0xed, 0, 0, 0, 2, 0, 0, 0, 0}, junk...)
+
+	if err := load(forged); !errors.Is(err, ErrInvalidDigest) {
+		t.Errorf("unexpected error for forged empty keystore: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package keystore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"sync"
+	"time"
+)
+
+// SyncKeyStore is a KeyStore safe for concurrent use by multiple goroutines.
+// Load reads the keystore into a new map and swaps it in only if the whole keystore is read successfully,
+// so readers never see partially loaded entries and the previous state is kept on errors.
+type SyncKeyStore struct {
+	mu      sync.RWMutex
+	ks      KeyStore
+	options []Option
+}
+
+// NewSync creates SyncKeyStore, the options are applied to the keystore created on every Load.
+func NewSync(options ...Option) *SyncKeyStore {
+	return &SyncKeyStore{
+		ks:      New(options...),
+		options: options,
+	}
+}
+
+// Load reads keystore representation from r and atomically replaces the entries with the read ones.
+// The entries are kept unchanged if the keystore can't be read.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) Load(r io.Reader, password []byte, options ...LoadOption) error {
+	ks := New(s.options...)
+
+	if err := ks.Load(r, password, options...); err != nil {
+		return err
+	}
+
+	s.Swap(ks)
+
+	return nil
+}
+
+// Swap atomically replaces the keystore with ks and returns the previous one.
+// ks must not be modified after the swap, use SyncKeyStore methods instead.
+func (s *SyncKeyStore) Swap(ks KeyStore) KeyStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.ks
+	s.ks = ks
+
+	return old
+}
+
+// Snapshot returns a copy of the keystore which is not affected by further changes of SyncKeyStore.
+func (s *SyncKeyStore) Snapshot() KeyStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.clone()
+}
+
+// Store signs keystore using password and writes its representation into w.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) Store(w io.Writer, password []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.Store(w, password)
+}
+
+// StoreType returns type of the keystore.
+func (s *SyncKeyStore) StoreType() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.StoreType()
+}
+
+// SetPrivateKeyEntry adds PrivateKeyEntry into keystore by alias encrypted with password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) SetPrivateKeyEntry(alias string, entry PrivateKeyEntry, password []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ks.SetPrivateKeyEntry(alias, entry, password)
+}
+
+// GetPrivateKeyEntry returns PrivateKeyEntry from the keystore by the alias decrypted with the password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) GetPrivateKeyEntry(alias string, password []byte) (PrivateKeyEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.GetPrivateKeyEntry(alias, password)
+}
+
+// IsPrivateKeyEntry returns true if the keystore has PrivateKeyEntry by the alias.
+func (s *SyncKeyStore) IsPrivateKeyEntry(alias string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.IsPrivateKeyEntry(alias)
+}
+
+// GetPrivateKeyEntryCertificateChain returns certificate chain of PrivateKeyEntry
+// from the keystore by the alias without decrypting the private key.
+func (s *SyncKeyStore) GetPrivateKeyEntryCertificateChain(alias string) ([]Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.GetPrivateKeyEntryCertificateChain(alias)
+}
+
+// SetTrustedCertificateEntry adds TrustedCertificateEntry into keystore by alias.
+func (s *SyncKeyStore) SetTrustedCertificateEntry(alias string, entry TrustedCertificateEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ks.SetTrustedCertificateEntry(alias, entry)
+}
+
+// GetTrustedCertificateEntry returns TrustedCertificateEntry from the keystore by the alias.
+func (s *SyncKeyStore) GetTrustedCertificateEntry(alias string) (TrustedCertificateEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.GetTrustedCertificateEntry(alias)
+}
+
+// IsTrustedCertificateEntry returns true if the keystore has TrustedCertificateEntry by the alias.
+func (s *SyncKeyStore) IsTrustedCertificateEntry(alias string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.IsTrustedCertificateEntry(alias)
+}
+
+// SetSecurityKeyEntry adds SecurityKeyEntry into JCEKS keystore by alias sealed with password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) SetSecurityKeyEntry(alias string, entry SecurityKeyEntry, password []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ks.SetSecurityKeyEntry(alias, entry, password)
+}
+
+// GetSecurityKeyEntry returns SecurityKeyEntry from the keystore by the alias unsealed with the password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) GetSecurityKeyEntry(alias string, password []byte) (SecurityKeyEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.GetSecurityKeyEntry(alias, password)
+}
+
+// IsSecurityKeyEntry returns true if the keystore has SecurityKeyEntry by the alias.
+func (s *SyncKeyStore) IsSecurityKeyEntry(alias string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.IsSecurityKeyEntry(alias)
+}
+
+// CreationTime returns creation time of the entry by the alias without decrypting it.
+func (s *SyncKeyStore) CreationTime(alias string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.CreationTime(alias)
+}
+
+// ChangeEntryPassword re-encrypts key of the entry by the alias with the new password.
+// It is strongly recommended to fill password slices with zero after usage.
+func (s *SyncKeyStore) ChangeEntryPassword(alias string, oldPassword, newPassword []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ks.ChangeEntryPassword(alias, oldPassword, newPassword)
+}
+
+// DeleteEntry deletes entry from the keystore.
+func (s *SyncKeyStore) DeleteEntry(alias string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ks.DeleteEntry(alias)
+}
+
+// Aliases returns slice of all aliases from the keystore.
+func (s *SyncKeyStore) Aliases() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.Aliases()
+}
+
+// TLSCertificate returns tls.Certificate built from PrivateKeyEntry by the alias decrypted with the password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (s *SyncKeyStore) TLSCertificate(alias string, password []byte) (tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.TLSCertificate(alias, password)
+}
+
+// CertPool returns pool of all TrustedCertificateEntry certificates.
+func (s *SyncKeyStore) CertPool() (*x509.CertPool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.CertPool()
+}
+
+// CertPoolWithChains returns pool of all TrustedCertificateEntry certificates
+// and CA certificates from PrivateKeyEntry chains, see KeyStore.CertPoolWithChains.
+func (s *SyncKeyStore) CertPoolWithChains() (*x509.CertPool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ks.CertPoolWithChains()
+}
+
+// clone returns keystore with the same options and a copy of the entries map and order.
+func (ks KeyStore) clone() KeyStore {
+	c := ks
+	c.m = make(map[string]interface{}, len(ks.m))
+
+	for alias, entry := range ks.m {
+		c.m[alias] = entry
+	}
+
+	if ks.order != nil {
+		c.order = ks.order.clone()
+	}
+
+	return c
+}
@@ -0,0 +1,163 @@
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func storeTrustedCertificates(t *testing.T, prefix string, n int, password []byte) []byte {
+	t.Helper()
+
+	ks := New()
+
+	for i := 0; i < n; i++ {
+		if err := ks.SetTrustedCertificateEntry(fmt.Sprintf("%s%d", prefix, i), TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSyncKeyStoreReload(t *testing.T) {
+	t.Parallel()
+
+	const entries = 20
+
+	password := []byte("password")
+	keystores := [][]byte{
+		storeTrustedCertificates(t, "a", entries, password),
+		storeTrustedCertificates(t, "b", entries, password),
+	}
+
+	s := NewSync()
+	if err := s.Load(bytes.NewReader(keystores[0]), password); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if _, err := s.CertPool(); err != nil {
+					t.Error(err)
+
+					return
+				}
+
+				aliases := s.Aliases()
+				if len(aliases) != entries {
+					t.Errorf("got %d aliases, half-loaded keystore is visible", len(aliases))
+
+					return
+				}
+
+				for _, alias := range aliases {
+					if alias[0] != aliases[0][0] {
+						t.Errorf("got mixed aliases %v", aliases)
+
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := s.Load(bytes.NewReader(keystores[i%2]), password); err != nil {
+			t.Error(err)
+		}
+
+		// A failed reload keeps the previous state.
+		if err := s.Load(bytes.NewReader(keystores[i%2][:100]), password); err == nil {
+			t.Error("load of truncated keystore should fail")
+		}
+	}
+
+	close(done)
+	wg.Wait()
+
+	if aliases := s.Aliases(); len(aliases) != entries || aliases[0][0] != 'b' {
+		t.Errorf("unexpected aliases after reloads: %v", aliases)
+	}
+}
+
+func TestSyncKeyStoreConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	s := NewSync(WithOrderedAliases())
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			alias := fmt.Sprintf("alias%d", i)
+
+			for j := 0; j < 20; j++ {
+				if err := s.SetTrustedCertificateEntry(alias, TrustedCertificateEntry{
+					CreationTime: time.Now(),
+					Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+				}); err != nil {
+					t.Error(err)
+
+					return
+				}
+
+				if !s.IsTrustedCertificateEntry(alias) {
+					t.Errorf("entry %q is not found", alias)
+				}
+
+				if _, err := s.CertPool(); err != nil {
+					t.Error(err)
+				}
+
+				snapshot := s.Snapshot()
+				snapshot.DeleteEntry(alias)
+
+				if err := s.Store(&bytes.Buffer{}, []byte("password")); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if aliases := s.Aliases(); len(aliases) != 8 {
+		t.Errorf("unexpected aliases %v", aliases)
+	}
+
+	old := s.Swap(New())
+	if len(old.Aliases()) != 8 || len(s.Aliases()) != 0 {
+		t.Error("unexpected keystore after swap")
+	}
+}
@@ -0,0 +1,23 @@
+#!/bin/sh
+# Generates keystores with Java keytool for interoperability tests.
+# Tests using the keystores are skipped until they are generated, run from the testdata directory:
+#   LC_ALL=C.UTF-8 ./keytool.sh
+set -eu
+
+# JCEKS keystore with a secret key stored as Java serialized SealedObject.
+rm -f keytool_seckey.jceks
+keytool -genseckey -keystore keytool_seckey.jceks -storetype JCEKS \
+	-storepass password -keypass password -alias aes -keyalg AES -keysize 128
+
+# PKCS12 keystore with a private key entry and a trusted certificate entry.
+rm -f keytool.p12 keytool_cert.der
+keytool -genkeypair -keystore keytool.p12 -storetype PKCS12 -storepass password \
+	-alias key -keyalg RSA -keysize 2048 -dname CN=keytool -validity 36500
+keytool -exportcert -keystore keytool.p12 -storepass password -alias key -file keytool_cert.der
+keytool -importcert -noprompt -keystore keytool.p12 -storepass password -alias ca -file keytool_cert.der
+rm -f keytool_cert.der
+
+# JKS keystore with non-ASCII store password, key password and alias with a supplementary character.
+rm -f keytool_unicode.jks
+keytool -genkeypair -keystore keytool_unicode.jks -storetype JKS -storepass 'пароль€' -keypass 'ключ-пароль' \
+	-alias 'ключ-😀' -keyalg RSA -keysize 2048 -dname CN=keytool -validity 36500
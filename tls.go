@@ -0,0 +1,56 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrEmptyCertificateChain = errors.New("empty certificate chain")
+	ErrKeyMismatch           = errors.New("private key does not match leaf certificate")
+)
+
+// TLSCertificate returns tls.Certificate built from PrivateKeyEntry by the alias decrypted with the password.
+// The private key must match the public key of the leaf certificate.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) TLSCertificate(alias string, password []byte) (tls.Certificate, error) {
+	pke, err := ks.GetPrivateKeyEntry(alias, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("get private key entry: %w", err)
+	}
+
+	defer zeroing(pke.PrivateKey)
+
+	if len(pke.CertificateChain) == 0 {
+		return tls.Certificate{}, ErrEmptyCertificateChain
+	}
+
+	signer, err := pke.Signer()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	chain, err := pke.Chain()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leafKey, ok := chain[0].PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !leafKey.Equal(signer.Public()) {
+		return tls.Certificate{}, ErrKeyMismatch
+	}
+
+	certificate := tls.Certificate{
+		Certificate: make([][]byte, 0, len(chain)),
+		PrivateKey:  signer,
+		Leaf:        chain[0],
+	}
+
+	for _, cert := range chain {
+		certificate.Certificate = append(certificate.Certificate, cert.Raw)
+	}
+
+	return certificate, nil
+}
@@ -0,0 +1,80 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTLSCertificate(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	f, err := os.Open("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatalf("open test data keystore file: %s", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatalf("close test data keystore file: %s", err)
+		}
+	}()
+
+	ks := New()
+	if err := ks.Load(f, password); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := ks.TLSCertificate("alias", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cert.Certificate) != 1 || cert.Leaf == nil || cert.PrivateKey == nil {
+		t.Fatalf("unexpected tls certificate: %+v", cert)
+	}
+
+	if cert.Leaf.Subject.CommonName != "localhost" {
+		t.Errorf("unexpected leaf subject: %v", cert.Leaf.Subject)
+	}
+
+	if _, err := ks.TLSCertificate("nonExistentAlias", password); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("unexpected error for non existent alias: %v", err)
+	}
+}
+
+func TestTLSCertificateKeyMismatch(t *testing.T) {
+	t.Parallel()
+
+	password := []byte("password")
+
+	ks := New()
+
+	pke := PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readCertificate(t)},
+		},
+	}
+
+	if err := ks.SetPrivateKeyEntry("mismatch", pke, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.TLSCertificate("mismatch", password); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("unexpected error for mismatched key: %v", err)
+	}
+
+	pke.CertificateChain = nil
+	if err := ks.SetPrivateKeyEntry("no-chain", pke, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.TLSCertificate("no-chain", password); !errors.Is(err, ErrEmptyCertificateChain) {
+		t.Errorf("unexpected error for empty chain: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package keystore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Verify checks integrity of the keystore read from r with the password without decoding its entries.
+// It returns false if the signature of JKS or JCEKS keystore or the mac of PKCS12 keystore does not match.
+// It is strongly recommended to fill password slice with zero after usage.
+func Verify(r io.Reader, password []byte) (bool, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := peekMagic(br)
+	if err != nil {
+		return false, err
+	}
+
+	storeType, err := detectStoreType(magic)
+	if err != nil {
+		return false, err
+	}
+
+	if storeType == PKCS12StoreType {
+		pfx, authSafeContent, err := readPKCS12(br)
+		if err != nil {
+			return false, err
+		}
+
+		return verifyPKCS12(pfx, authSafeContent, password)
+	}
+
+	signReader, err := newVerifyingReader(br, password)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := io.Copy(ioutil.Discard, signReader); err != nil {
+		return false, fmt.Errorf("read keystore: %w", err)
+	}
+
+	verified, err := signReader.VerifySign()
+	if err != nil {
+		return false, fmt.Errorf("read digest: %w", err)
+	}
+
+	return verified, nil
+}
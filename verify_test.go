@@ -0,0 +1,75 @@
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"./testdata/keystore.jks", "./testdata/keystore_keypass.jks", "./testdata/keystore.p12"}
+
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verified, err := Verify(bytes.NewReader(data), []byte("password"))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if !verified {
+			t.Errorf("%s: should be verified with the password", name)
+		}
+
+		verified, err = Verify(bytes.NewReader(data), []byte("wrongpassword"))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if verified {
+			t.Errorf("%s: should not be verified with wrong password", name)
+		}
+	}
+}
+
+func TestLoadWithoutIntegrityCheck(t *testing.T) {
+	t.Parallel()
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New()
+	if err := ks.Load(bytes.NewReader(data), nil); err == nil {
+		t.Error("should fail without password")
+	}
+
+	ks = New()
+	if err := ks.Load(bytes.NewReader(data), nil, WithoutIntegrityCheck()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.GetPrivateKeyEntry("alias", []byte("keypassword")); err != nil {
+		t.Errorf("private key should be decrypted with its password: %s", err)
+	}
+
+	p12, err := ioutil.ReadFile("./testdata/keystore.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks = New()
+	if err := ks.Load(bytes.NewReader(p12), []byte("password"), WithoutIntegrityCheck()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ks.IsPrivateKeyEntry("alias") {
+		t.Error("pkcs12 keystore should be loaded")
+	}
+}
@@ -0,0 +1,316 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultPollInterval = 10 * time.Second
+
+var ErrNoCertificate = errors.New("no certificate in keystore")
+
+// WatchEvent describes a reload of the keystore file by Watcher.
+// Added, Removed and Changed contain sorted aliases of the affected entries.
+// KeyErrors contains errors of PrivateKeyEntry entries by their aliases which can't be used as certificates,
+// e.g. keys protected with passwords other than the key password, such entries are skipped.
+// CertErrors contains errors of TrustedCertificateEntry certificates by their aliases which can't be parsed,
+// they are left out of RootCAs.
+// Err is set if the reload failed, the last good keystore is kept in this case.
+type WatchEvent struct {
+	Added      []string
+	Removed    []string
+	Changed    []string
+	KeyErrors  map[string]error
+	CertErrors map[string]error
+	Err        error
+}
+
+// Watcher polls a keystore file and reloads it when its content changes.
+// The keystore is verified with the store password, if it can't be loaded the last good state is kept.
+// Watcher is safe for concurrent use by multiple goroutines.
+type Watcher struct {
+	path         string
+	password     []byte
+	keyPassword  []byte
+	interval     time.Duration
+	options      []Option
+	loadOptions  []LoadOption
+	onChange     func(WatchEvent)
+	keyStore     *SyncKeyStore
+	reloadMu     sync.Mutex
+	hash         [sha256.Size]byte
+	failedHash   [sha256.Size]byte
+	failedErr    error
+	mu           sync.RWMutex
+	certificates []tls.Certificate
+	rootCAs      *x509.CertPool
+}
+
+type WatcherOption func(w *Watcher)
+
+// WithPollInterval sets interval of checking the keystore file for changes, the default is 10 seconds.
+// Non-positive intervals are ignored and the default is kept.
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// WithKeyPassword sets password of PrivateKeyEntry keys, the store password is used by default.
+func WithKeyPassword(password []byte) WatcherOption {
+	return func(w *Watcher) { w.keyPassword = password }
+}
+
+// WithKeyStoreOptions sets options of the keystore created on every reload.
+func WithKeyStoreOptions(options ...Option) WatcherOption {
+	return func(w *Watcher) { w.options = options }
+}
+
+// WithLoadOptions sets options of KeyStore.Load used on every reload.
+func WithLoadOptions(options ...LoadOption) WatcherOption {
+	return func(w *Watcher) { w.loadOptions = options }
+}
+
+// WithOnChange sets function called after every reload which changed entries, skipped keys or certificates or failed.
+// It is called synchronously from NewWatcher, Reload and Run, the initial load reports all entries as added.
+func WithOnChange(onChange func(WatchEvent)) WatcherOption {
+	return func(w *Watcher) { w.onChange = onChange }
+}
+
+// NewWatcher loads the keystore file from path and returns Watcher of it.
+// Password slices are used on every reload, so they must not be changed while Watcher is in use.
+func NewWatcher(path string, password []byte, options ...WatcherOption) (*Watcher, error) {
+	w := &Watcher{
+		path:     path,
+		password: password,
+		interval: defaultPollInterval,
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+
+	if w.keyPassword == nil {
+		w.keyPassword = password
+	}
+
+	w.keyStore = NewSync(w.options...)
+
+	if _, err := w.reload(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Run checks the keystore file for changes every poll interval until ctx is done.
+// Reload errors are reported to the function set by WithOnChange.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_ = w.Reload()
+		}
+	}
+}
+
+// Reload reads the keystore file and replaces the keystore if the file content has changed.
+// If the keystore can't be loaded the error is returned and the last good state is kept.
+// The failed file content is not loaded again until it changes, its error is reported by WithOnChange function once.
+func (w *Watcher) Reload() error {
+	retried, err := w.reload()
+	if err != nil && !retried {
+		w.notify(WatchEvent{Err: err})
+	}
+
+	return err
+}
+
+// reload loads the keystore file if its content has changed, it returns true with the error of the previous reload
+// if the content is the same as of the failed one.
+func (w *Watcher) reload() (bool, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return false, fmt.Errorf("read keystore file: %w", err)
+	}
+
+	hash := sha256.Sum256(data)
+
+	switch {
+	case hash == w.hash:
+		return false, nil
+	case hash == w.failedHash:
+		return true, w.failedErr
+	}
+
+	if err := w.load(data); err != nil {
+		w.failedHash, w.failedErr = hash, err
+
+		return false, err
+	}
+
+	w.hash = hash
+	w.failedHash, w.failedErr = [sha256.Size]byte{}, nil
+
+	return false, nil
+}
+
+func (w *Watcher) load(data []byte) error {
+	ks := New(w.options...)
+	if err := ks.Load(bytes.NewReader(data), w.password, w.loadOptions...); err != nil {
+		return fmt.Errorf("load keystore: %w", err)
+	}
+
+	aliases := ks.Aliases()
+	sort.Strings(aliases)
+
+	certificates := make([]tls.Certificate, 0, len(aliases))
+	keyErrors := make(map[string]error)
+
+	for _, alias := range aliases {
+		if !ks.IsPrivateKeyEntry(alias) {
+			continue
+		}
+
+		certificate, err := ks.TLSCertificate(alias, w.keyPassword)
+		if err != nil {
+			keyErrors[alias] = err
+
+			continue
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	var certErrors ErrInvalidCertificates
+
+	rootCAs, err := ks.CertPool()
+	if err != nil && !errors.As(err, &certErrors) {
+		return fmt.Errorf("get root CAs: %w", err)
+	}
+
+	w.mu.Lock()
+	w.certificates = certificates
+	w.rootCAs = rootCAs
+	old := w.keyStore.Swap(ks)
+	w.mu.Unlock()
+
+	event := diffKeyStores(old, ks)
+	if len(keyErrors) > 0 {
+		event.KeyErrors = keyErrors
+	}
+
+	if len(certErrors) > 0 {
+		event.CertErrors = certErrors
+	}
+
+	if len(event.Added)+len(event.Removed)+len(event.Changed)+len(event.KeyErrors)+len(event.CertErrors) > 0 {
+		w.notify(event)
+	}
+
+	return nil
+}
+
+func (w *Watcher) notify(event WatchEvent) {
+	if w.onChange != nil {
+		w.onChange(event)
+	}
+}
+
+// KeyStore returns the last successfully loaded keystore.
+func (w *Watcher) KeyStore() *SyncKeyStore {
+	return w.keyStore
+}
+
+// RootCAs returns pool of TrustedCertificateEntry certificates of the last successfully loaded keystore.
+// The pool is replaced on reload, so call RootCAs for every connection,
+// e.g. from tls.Config.GetConfigForClient or VerifyPeerCertificate.
+func (w *Watcher) RootCAs() *x509.CertPool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.rootCAs
+}
+
+// GetCertificate returns certificate of PrivateKeyEntry supported by the client, aliases are tried alphabetically.
+// The first certificate is returned if none is supported. It is suitable for tls.Config.GetCertificate.
+func (w *Watcher) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return w.certificate(func(certificate *tls.Certificate) bool {
+		return hello.SupportsCertificate(certificate) == nil
+	})
+}
+
+// GetClientCertificate returns certificate of PrivateKeyEntry supported by the server,
+// aliases are tried alphabetically. The first certificate is returned if none is supported.
+// It is suitable for tls.Config.GetClientCertificate.
+func (w *Watcher) GetClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return w.certificate(func(certificate *tls.Certificate) bool {
+		return info.SupportsCertificate(certificate) == nil
+	})
+}
+
+func (w *Watcher) certificate(supports func(*tls.Certificate) bool) (*tls.Certificate, error) {
+	w.mu.RLock()
+	certificates := w.certificates
+	w.mu.RUnlock()
+
+	if len(certificates) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	for i := range certificates {
+		if supports(&certificates[i]) {
+			return &certificates[i], nil
+		}
+	}
+
+	return &certificates[0], nil
+}
+
+// diffKeyStores returns aliases of entries added, removed or changed in the new keystore.
+func diffKeyStores(prev, next KeyStore) WatchEvent {
+	var event WatchEvent
+
+	for alias, entry := range next.m {
+		prevEntry, ok := prev.m[alias]
+
+		switch {
+		case !ok:
+			event.Added = append(event.Added, alias)
+		case !reflect.DeepEqual(prevEntry, entry):
+			event.Changed = append(event.Changed, alias)
+		}
+	}
+
+	for alias := range prev.m {
+		if _, ok := next.m[alias]; !ok {
+			event.Removed = append(event.Removed, alias)
+		}
+	}
+
+	sort.Strings(event.Added)
+	sort.Strings(event.Removed)
+	sort.Strings(event.Changed)
+
+	return event
+}
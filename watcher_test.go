@@ -0,0 +1,286 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "keystore.jks")
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	password := []byte("password")
+	events := make(chan WatchEvent, 10)
+
+	w, err := NewWatcher(path, password,
+		WithKeyPassword([]byte("keypassword")),
+		WithPollInterval(10*time.Millisecond),
+		WithOnChange(func(event WatchEvent) { events <- event }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := <-events; !reflect.DeepEqual(event.Added, []string{"alias"}) {
+		t.Errorf("unexpected initial event %+v", event)
+	}
+
+	certificate, err := w.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pke, err := w.KeyStore().GetPrivateKeyEntry("alias", []byte("keypassword"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(certificate.Certificate[0], pke.CertificateChain[0].Content) {
+		t.Error("unexpected certificate")
+	}
+
+	ks := w.KeyStore().Snapshot()
+	if err := ks.SetTrustedCertificateEntry("ca", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = w.Run(ctx) }()
+
+	select {
+	case event := <-events:
+		if !reflect.DeepEqual(event.Added, []string{"ca"}) || event.Removed != nil || event.Changed != nil {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("keystore is not reloaded")
+	}
+
+	if len(w.RootCAs().Subjects()) != 1 { //nolint:staticcheck
+		t.Error("root CAs are not reloaded")
+	}
+
+	cancel()
+
+	if err := ioutil.WriteFile(path, buf.Bytes()[:100], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("reload of truncated keystore should fail")
+	}
+
+	if event := <-events; event.Err == nil {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("reload of the same truncated keystore should fail")
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("failure of the same keystore is reported again %+v", event)
+	default:
+	}
+
+	if _, err := w.GetClientCertificate(&tls.CertificateRequestInfo{}); err != nil {
+		t.Errorf("last good certificate is not kept: %v", err)
+	}
+
+	if !w.KeyStore().IsTrustedCertificateEntry("ca") {
+		t.Error("last good keystore is not kept")
+	}
+}
+
+func TestWatcherKeyErrors(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	f, err := os.Open("./testdata/keystore_keypass.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	password := []byte("password")
+	ks := New()
+
+	if err := ks.Load(f, password); err != nil {
+		t.Fatal(err)
+	}
+
+	pke, err := ks.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.SetPrivateKeyEntry("other", pke, []byte("otherpassword")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "keystore.jks")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan WatchEvent, 10)
+
+	w, err := NewWatcher(path, password,
+		WithKeyPassword([]byte("keypassword")),
+		WithOnChange(func(event WatchEvent) { events <- event }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-events
+	if !reflect.DeepEqual(event.Added, []string{"alias", "other"}) || len(event.KeyErrors) != 1 ||
+		!errors.Is(event.KeyErrors["other"], ErrWrongPassword) {
+		t.Errorf("unexpected initial event %+v", event)
+	}
+
+	certificate, err := w.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(certificate.Certificate[0], pke.CertificateChain[0].Content) {
+		t.Error("unexpected certificate")
+	}
+}
+
+func TestWatcherCertErrors(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	password := []byte("password")
+	ks := New()
+
+	ca := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+	}
+	if err := ks.SetTrustedCertificateEntry("ca", ca); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: []byte{1, 2, 3}},
+	}
+	if err := ks.SetTrustedCertificateEntry("invalid", invalid); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, password); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "keystore.jks")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan WatchEvent, 10)
+
+	w, err := NewWatcher(path, password, WithOnChange(func(event WatchEvent) { events <- event }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-events
+	if !reflect.DeepEqual(event.Added, []string{"ca", "invalid"}) || len(event.CertErrors) != 1 ||
+		event.CertErrors["invalid"] == nil || event.Err != nil {
+		t.Errorf("unexpected initial event %+v", event)
+	}
+
+	cert, err := ca.Certificate.X509()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subjects := w.RootCAs().Subjects() // nolint: staticcheck
+	if len(subjects) != 1 || !bytes.Equal(subjects[0], cert.RawSubject) {
+		t.Error("valid certificate is not in root CAs")
+	}
+}
+
+func TestWatcherPollInterval(t *testing.T) {
+	t.Parallel()
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		w, err := NewWatcher("./testdata/keystore_keypass.jks", []byte("password"), WithPollInterval(interval))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if w.interval != defaultPollInterval {
+			t.Errorf("unexpected poll interval %v for %v", w.interval, interval)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := w.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected run error: %v", err)
+		}
+	}
+}
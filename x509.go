@@ -0,0 +1,62 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsupportedCertificateType = errors.New("unsupported certificate type")
+	ErrUnsupportedPrivateKey      = errors.New("unsupported private key")
+)
+
+// X509 parses certificate content. It returns ErrUnsupportedCertificateType for non X.509 certificates.
+func (c Certificate) X509() (*x509.Certificate, error) {
+	if !isX509Certificate(c) {
+		return nil, fmt.Errorf("got certificate of type %s: %w", c.Type, ErrUnsupportedCertificateType)
+	}
+
+	cert, err := x509.ParseCertificate(c.Content)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// Signer parses PKCS#8 private key of the entry returned by GetPrivateKeyEntry.
+func (e PrivateKeyEntry) Signer() (crypto.Signer, error) {
+	if len(e.PrivateKey) == 0 {
+		return nil, ErrEmptyPrivateKey
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(e.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("got private key of type %T: %w", key, ErrUnsupportedPrivateKey)
+	}
+
+	return signer, nil
+}
+
+// Chain parses certificate chain of the entry, the leaf certificate goes first.
+func (e PrivateKeyEntry) Chain() ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0, len(e.CertificateChain))
+
+	for i, c := range e.CertificateChain {
+		cert, err := c.X509()
+		if err != nil {
+			return nil, fmt.Errorf("parse %d certificate in chain: %w", i, err)
+		}
+
+		chain = append(chain, cert)
+	}
+
+	return chain, nil
+}
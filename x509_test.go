@@ -0,0 +1,69 @@
+package keystore
+
+import (
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func TestCertificateX509(t *testing.T) {
+	t.Parallel()
+
+	cert, err := Certificate{Type: "X.509", Content: readCertificate(t)}.X509()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cert.Subject.CommonName != "localhost" {
+		t.Errorf("unexpected subject: %v", cert.Subject)
+	}
+
+	_, err = Certificate{Type: "PGP", Content: readCertificate(t)}.X509()
+	if !errors.Is(err, ErrUnsupportedCertificateType) {
+		t.Errorf("unexpected error for non x509 certificate: %v", err)
+	}
+}
+
+func TestPrivateKeyEntrySignerAndChain(t *testing.T) {
+	t.Parallel()
+
+	pke := PrivateKeyEntry{
+		PrivateKey: readPEMBlock(t, "./testdata/key_pkcs12.pem", "PRIVATE KEY"),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readPEMBlock(t, "./testdata/cert_pkcs12.pem", "CERTIFICATE")},
+			{Type: "X509", Content: readCertificate(t)},
+		},
+	}
+
+	signer, err := pke.Signer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := signer.(*rsa.PrivateKey); !ok {
+		t.Errorf("unexpected signer type %T", signer)
+	}
+
+	chain, err := pke.Chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chain) != 2 {
+		t.Fatalf("unexpected chain length: %d", len(chain))
+	}
+
+	leafKey, ok := chain[0].PublicKey.(*rsa.PublicKey)
+	if !ok || !leafKey.Equal(signer.Public()) {
+		t.Error("leaf certificate must match private key")
+	}
+
+	if _, err := (PrivateKeyEntry{}).Signer(); !errors.Is(err, ErrEmptyPrivateKey) {
+		t.Errorf("unexpected error for empty private key: %v", err)
+	}
+
+	pke.CertificateChain[1].Type = "PGP"
+	if _, err := pke.Chain(); !errors.Is(err, ErrUnsupportedCertificateType) {
+		t.Errorf("unexpected error for non x509 chain: %v", err)
+	}
+}